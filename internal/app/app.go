@@ -11,13 +11,16 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/lex-unix/faino/internal/command"
 	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/engine"
 	"github.com/lex-unix/faino/internal/exec/localexec"
 	"github.com/lex-unix/faino/internal/exec/sshexec"
 	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/notify"
 	"github.com/lex-unix/faino/internal/stream"
 	"github.com/lex-unix/faino/internal/template"
 	"github.com/lex-unix/faino/internal/txman"
@@ -26,23 +29,48 @@ import (
 type App struct {
 	txmanager txman.Service
 	lexec     localexec.Service
+	notifier  notify.Notifier
+	// progress receives step events of deploy and rollback transactions
+	progress txman.ProgressFunc
+
+	// gitInfo is the local commit and operator, read once by loadGitInfo
+	gitInfoOnce sync.Once
+	gitInfo     gitInfo
+
+	// engines caches the container engine of every host, so that an unreachable
+	// Docker API is only tried and reported once
+	enginesMu sync.Mutex
+	engines   map[string]engine.Engine
 
 	history         []HistoryEntry
 	historySorted   bool
 	historyFilePath string
+	auditFilePath   string
 }
 
-func New(lexec localexec.Service, txmanager txman.Service) *App {
+func New(lexec localexec.Service, txmanager txman.Service, notifier notify.Notifier) *App {
 	a := &App{
 		lexec:           lexec,
 		txmanager:       txmanager,
+		notifier:        notifier,
 		historyFilePath: defautlHistoryFilePath,
+		auditFilePath:   defaultAuditFilePath,
 		historySorted:   false,
 	}
 
 	return a
 }
 
+// SetProgress reports progress of deploy and rollback steps to fn.
+func (app *App) SetProgress(fn txman.ProgressFunc) {
+	app.progress = fn
+}
+
+// ObserveTransactions registers o to receive events of every transaction.
+func (app *App) ObserveTransactions(o txman.Observer) {
+	app.txmanager.Observe(o)
+}
+
 const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
 var seededRand = rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -57,22 +85,81 @@ func generateRandomString(length int) string {
 
 type HostOutput map[string]string
 
-func (app *App) Deploy(ctx context.Context) error {
+type DeployOptions struct {
+	// Note is stored in history entry of the deployed version
+	Note string
+}
+
+func (app *App) Deploy(ctx context.Context, opts DeployOptions) error {
+	// FIXME: should use commit hash for this
+	newVersion := generateRandomString(10)
+	started := time.Now()
+	op := app.beginAudit(AuditDeploy, newVersion, "")
+
+	app.notify(ctx, notify.EventDeployStart, newVersion, 0, nil)
+	deployCtx, cancel := withTimeout(ctx, config.Get().Deploy.Timeout)
+	err := app.deploy(deployCtx, newVersion, opts)
+	cancel()
+	app.finishAudit(ctx, op, err)
+	var partialErr *txman.PartialError
+	if err != nil && !errors.As(err, &partialErr) {
+		app.notify(ctx, notify.EventDeployFailure, newVersion, time.Since(started), err)
+		return err
+	}
+
+	// the deploy is committed, so post-deploy hooks can no longer roll it back
+	hc := hookContext{name: HookPostDeploy, version: newVersion, image: imageName(newVersion)}
+	if hookErr := app.runHooks(ctx, config.Get().Hooks.PostDeploy, hc); hookErr != nil {
+		err = errors.Join(err, &PostDeployError{Err: hookErr})
+	}
+	app.notify(ctx, notify.EventDeploySuccess, newVersion, time.Since(started), err)
+
+	return err
+}
+
+// PostDeployError is returned by Deploy if the deploy succeeded, but a post-deploy hook failed.
+type PostDeployError struct {
+	Err error
+}
+
+func (e *PostDeployError) Error() string {
+	return fmt.Sprintf("app deployed, but %s", e.Err)
+}
+
+func (e *PostDeployError) Unwrap() error {
+	return e.Err
+}
+
+func (app *App) deploy(ctx context.Context, newVersion string, opts DeployOptions) error {
 	cfg := config.Get()
 
+	if !cfg.Preflight.Skip {
+		logging.Info("running preflight checks...")
+		report, err := app.Preflight(ctx)
+		if err != nil {
+			return err
+		}
+		if report.Failed() {
+			return &PreflightError{Report: report}
+		}
+	}
+
 	err := app.LoadHistory(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to read history at %s: %w", app.historyFilePath, err)
 	}
 
-	// FIXME: should use commit hash for this
 	currentVersion := app.LatestVersion()
 	logging.Debugf("current version of app is %s", currentVersion)
-	newVersion := generateRandomString(10)
-	logging.Debugf("new version of app is %s", currentVersion)
-	image := fmt.Sprintf("%s/%s/%s:%s", cfg.Registry.Server, cfg.Registry.Username, cfg.Image, newVersion)
-	currentContainer := fmt.Sprintf("%s-%s", cfg.Service, currentVersion)
+	logging.Debugf("new version of app is %s", newVersion)
+	image := imageName(newVersion)
 	newContainer := fmt.Sprintf("%s-%s", cfg.Service, newVersion)
+	runtime := serverRuntime()
+
+	uploads, err := collectFiles(cfg.Files)
+	if err != nil {
+		return err
+	}
 
 	if cfg.Build.Driver != "docker" {
 		// check if builder exists
@@ -92,6 +179,11 @@ func (app *App) Deploy(ctx context.Context) error {
 		}
 	}
 
+	err = app.runHooks(ctx, cfg.Hooks.PreBuild, hookContext{name: HookPreBuild, version: newVersion, image: image})
+	if err != nil {
+		return err
+	}
+
 	env := make([]string, 0)
 	for k, v := range cfg.Build.Secrets {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
@@ -111,93 +203,209 @@ func (app *App) Deploy(ctx context.Context) error {
 
 	// check if proxy is running, start or run it if not
 	err = app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
-		var out bytes.Buffer
-		err := client.Run(ctx, command.ListRunningContainers(), sshexec.WithStdout(&out))
-		if err != nil {
+		eng := app.containerEngine(client)
+		proxy, err := eng.ContainerInspect(ctx, cfg.Proxy.Container)
+		switch {
+		case errors.Is(err, engine.ErrNotFound):
+			// proxy container not found, run it
+			return client.Run(ctx, serverRuntime().RunProxy(cfg.Proxy.Img, cfg.Proxy.Container, cfg.Proxy.Labels, cfg.Proxy.Args))
+		case err != nil:
 			return err
+		case !proxy.Running():
+			return eng.ContainerStart(ctx, cfg.Proxy.Container)
 		}
-		// proxy is running
-		if strings.Contains(out.String(), cfg.Proxy.Container) {
-			return nil
-		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	git := app.loadGitInfo(ctx)
+	entry := HistoryEntry{
+		Version:     newVersion,
+		Image:       image,
+		Deployer:    git.operator,
+		Commit:      git.commit,
+		Volumes:     cfg.Volumes,
+		DeployedAt:  time.Now(),
+		ActivatedAt: time.Now(),
+		Status:      HistoryStatusActive,
+		Notes:       opts.Note,
+	}
+	history := app.historyWith(entry, HistoryStatusInactive)
+	historyData, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
 
-		out.Reset()
+	preDeployHooks := app.hookCallbacks(cfg.Hooks.PreDeploy, hookContext{name: HookPreDeploy, version: newVersion, image: image})
 
-		// check if proxy is stopped
-		err = client.Run(ctx, command.ListAllContainers(), sshexec.WithStdout(&out))
+	rollback, err := app.txmanager.BeginTransaction(ctx, func(ctx context.Context, tx txman.Transaction) error {
+		// prepare phase, current version keeps running
+		err := tx.Do(ctx, pullImage(image), nil, txman.WithName("pull image"), txman.WithTimeout(cfg.Deploy.PullTimeout))
 		if err != nil {
 			return err
 		}
-
-		// proxy is stopped, start it
-		if strings.Contains(out.String(), cfg.Proxy.Container) {
-			return client.Run(ctx, command.StartContainer(cfg.Proxy.Container))
+		err = pushFilesTx(ctx, tx, uploads)
+		if err != nil {
+			return err
 		}
-
-		// proxy container not found, run it
-		err = client.Run(ctx, command.RunProxy(cfg.Proxy.Img, cfg.Proxy.Container, cfg.Proxy.Labels, cfg.Proxy.Args))
+		err = releaseEnvTx(ctx, tx, newVersion, cfg.Env)
+		if err != nil {
+			return err
+		}
+		err = tx.Run(ctx, runtime.CreateContainer(image, newContainer, cfg.Service, cfg.Env, cfg.Volumes), runtime.RemoveContainer(newContainer), txman.WithName("create new container"))
+		if err != nil {
+			return err
+		}
+		err = app.runHooksTx(ctx, tx, HookPreDeploy, preDeployHooks)
 		if err != nil {
 			return err
 		}
-		return nil
-	})
-
-	if err != nil {
-		return err
-	}
 
-	rollback, err := app.txmanager.BeginTransaction(ctx, func(ctx context.Context, tx txman.Transaction) error {
-		err := tx.Run(ctx, command.PullImage(image), "")
+		// commit phase starts once every server is prepared, so that a slow
+		// pull on one server does not delay the switch on the others
+		err = tx.Barrier(ctx)
 		if err != nil {
 			return err
 		}
-		err = tx.Run(ctx, command.StopContainer(currentContainer), command.StartContainer(currentContainer))
+		// servers that failed the previous deploy still run an older version
+		currentContainer := fmt.Sprintf("%s-%s", cfg.Service, app.runningVersion(tx.Host()))
+		err = tx.Run(ctx, runtime.StopContainer(currentContainer), runtime.StartContainer(currentContainer), txman.WithName("stop current container"))
 		if err != nil {
 			return err
 		}
-		err = tx.Run(ctx, command.RunContainer(image, newContainer, cfg.Service, cfg.Env, cfg.Volumes), command.StopContainer(newContainer))
+		err = tx.Run(ctx, runtime.StartContainer(newContainer), runtime.StopContainer(newContainer), txman.WithName("start new container"))
 		if err != nil {
 			return err
 		}
-
-		err = tx.Do(ctx, app.AppendVersion(newVersion), nil)
+		err = cronTx(ctx, tx, newVersion)
+		if err != nil {
+			return err
+		}
+		err = tx.Do(ctx, WriteToRemoteFile(app.historyFilePath, historyData, historyFileMode), nil, txman.WithName("record history"))
 		if err != nil {
 			return err
 		}
 
 		return nil
-	})
+	}, txman.WithStepTimeout(cfg.Deploy.StepTimeout), txman.WithQuorum(cfg.Deploy.Quorum), txman.WithProgress(app.progress))
+
+	var partialErr *txman.PartialError
+	if errors.As(err, &partialErr) {
+		logging.Warnf("deploy failed on %s, rolling back these servers", strings.Join(partialErr.FailedHosts(), ", "))
+		rollbackCtx, rollbackCancel := withTimeout(context.Background(), cfg.Rollback.Timeout)
+		defer rollbackCancel()
+		rollbackErr := rollback(rollbackCtx)
+
+		// failed servers were rolled back and keep running the version they had
+		entry.FailedHosts = partialErr.FailedHosts()
+		entry.Kept = app.keptVersions(entry.FailedHosts)
+		app.recordHistoryEntry(rollbackCtx, entry, HistoryStatusInactive)
+		return errors.Join(err, rollbackErr)
+	}
 
 	if err != nil {
 		logging.Info("initiating rollback...")
-		rollbackCtx, rollbackCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		rollbackCtx, rollbackCancel := withTimeout(context.Background(), cfg.Rollback.Timeout)
 		defer rollbackCancel()
-		if err := rollback(rollbackCtx); err != nil {
-			return err
+		if rollbackErr := rollback(rollbackCtx); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+
+		entry.Status = HistoryStatusFailed
+		entry.ActivatedAt = time.Time{}
+		app.recordHistoryEntry(rollbackCtx, entry, HistoryStatusInactive)
+
+		if currentVersion != "" {
+			hc := hookContext{name: HookPostRollback, version: currentVersion, image: imageName(currentVersion)}
+			if hookErr := app.runHooks(rollbackCtx, cfg.Hooks.PostRollback, hc); hookErr != nil {
+				return errors.Join(err, hookErr)
+			}
 		}
 
 		// return the original error that caused transaction to fail
 		return err
 	}
 
+	app.setHistory(history)
 	return nil
 }
 
+// recordHistoryEntry adds entry to history on every host outside of a transaction,
+// so that failed and partial deploys are visible in `faino history`. Errors are only logged.
+func (app *App) recordHistoryEntry(ctx context.Context, entry HistoryEntry, previousStatus string) {
+	history := app.historyWith(entry, previousStatus)
+	data, err := json.Marshal(history)
+	if err != nil {
+		logging.Warnf("failed to marshal history: %s", err)
+		return
+	}
+	err = app.txmanager.Execute(ctx, WriteToRemoteFile(app.historyFilePath, data, historyFileMode))
+	if err != nil {
+		logging.Warnf("failed to record deploy in history: %s", err)
+		return
+	}
+	app.setHistory(history)
+}
+
+// withTimeout returns ctx with deadline d from now, zero d means no deadline.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// imageName returns registry image of the service tagged with version.
+func imageName(version string) string {
+	cfg := config.Get()
+	return fmt.Sprintf("%s/%s/%s:%s", cfg.Registry.Server, cfg.Registry.Username, cfg.Image, version)
+}
+
+// serverRuntime returns runtime of containers on servers, Docker if it is not configured.
+func serverRuntime() command.Runtime {
+	return command.ParseRuntime(config.Get().Runtime)
+}
+
+// containerEngine returns engine of the host client is connected to. It talks to
+// the Docker Engine API if it is enabled in config.
+func (app *App) containerEngine(client sshexec.Service) engine.Engine {
+	app.enginesMu.Lock()
+	defer app.enginesMu.Unlock()
+
+	if eng, ok := app.engines[client.Host()]; ok {
+		return eng
+	}
+	opts := []engine.Option{engine.WithRuntime(serverRuntime())}
+	if config.Get().Engine == "api" {
+		opts = append(opts, engine.WithAPI())
+	}
+	eng := engine.New(client, opts...)
+	if app.engines == nil {
+		app.engines = make(map[string]engine.Engine)
+	}
+	app.engines[client.Host()] = eng
+	return eng
+}
+
 // Setup should be safe to run multiple times without destructive opeations.
 // For example, if a history file is present, it must not overwrite it.
-func (app *App) Setup(ctx context.Context) error {
-	return app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
-		err := client.Run(ctx, command.CreateNetwork())
+// The returned report has Docker readiness of every host, even if setup failed.
+func (app *App) Setup(ctx context.Context, opts SetupOptions) (SetupReport, error) {
+	reporter := &setupReporter{report: SetupReport{}}
+	err := app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
+		readiness, err := ensureRuntime(ctx, client, opts)
+		readiness.Err = err
+		reporter.set(client.Host(), readiness)
 		if err != nil {
-			var sshErr *sshexec.CommandError
-			switch {
-			case errors.As(err, &sshErr):
-				if !strings.Contains(sshErr.Msg, "already exists") {
-					return err
-				}
-			default:
-				return err
-			}
+			return err
+		}
+
+		_, err = app.containerEngine(client).NetworkCreate(ctx, command.Network)
+		if err != nil && !errors.Is(err, engine.ErrConflict) {
+			return err
 		}
 
 		err = client.Run(ctx, command.Mkdir("~/.faino"))
@@ -206,31 +414,37 @@ func (app *App) Setup(ctx context.Context) error {
 		}
 
 		// check if history file exists, create if doesn't
-		file, err := client.ReadFile(app.historyFilePath)
-		if err != nil {
-			var sshErr *sshexec.CommandError
-			switch {
-			case errors.As(err, &sshErr):
-				if strings.Contains(sshErr.Msg, "No such file or directory") {
-					return client.Run(ctx, command.CreateFileWithContents(app.historyFilePath, "[]"))
-				}
-			default:
-				return err
-			}
+		file, err := client.ReadFile(ctx, app.historyFilePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
 		}
 
-		file = bytes.TrimSpace(file)
-
-		// if file exists but is empty, initialize it
-		if len(file) == 0 {
-			return client.Run(ctx, command.CreateFileWithContents(app.historyFilePath, "[]"))
+		// if file does not exist or is empty, initialize it
+		if len(bytes.TrimSpace(file)) == 0 {
+			return client.WriteFile(ctx, app.historyFilePath, []byte("[]"), historyFileMode)
 		}
 
 		return nil
 	})
+	if err != nil {
+		return reporter.report, err
+	}
+
+	return reporter.report, app.PushFiles(ctx)
 }
 
 func (app *App) Rollback(ctx context.Context, version string) error {
+	started := time.Now()
+	op := app.beginAudit(AuditRollback, version, "")
+	rollbackCtx, cancel := withTimeout(ctx, config.Get().Rollback.Timeout)
+	err := app.rollbackTo(rollbackCtx, version)
+	cancel()
+	app.finishAudit(ctx, op, err)
+	app.notify(ctx, notify.EventRollback, version, time.Since(started), err)
+	return err
+}
+
+func (app *App) rollbackTo(ctx context.Context, version string) error {
 	err := app.LoadHistory(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to read history at %s: %w", app.historyFilePath, err)
@@ -240,63 +454,98 @@ func (app *App) Rollback(ctx context.Context, version string) error {
 	if found < 0 {
 		return fmt.Errorf("version %s does not exist", version)
 	}
-	// set timestamp for rolled version to current time
-	app.history[found].Timestamp = time.Now()
-	history, err := json.Marshal(app.history)
+	switch app.history[found].Status {
+	case HistoryStatusActive:
+		return fmt.Errorf("version %s is already active", version)
+	case HistoryStatusFailed:
+		return fmt.Errorf("version %s failed to deploy", version)
+	}
+	target := app.history[found]
+	states, err := app.inspectRollbackTarget(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	history := app.historyWithActive(version, HistoryStatusRolledBack)
+	historyData, err := json.Marshal(history)
 	if err != nil {
 		return err
 	}
 
 	cfg := config.Get()
-	currentVersion := app.LatestVersion()
-	service := cfg.Service
-	currentContainer := fmt.Sprintf("%s-%s", service, currentVersion)
-	newContainer := fmt.Sprintf("%s-%s", service, version)
+	runtime := serverRuntime()
 
 	rollback, err := app.txmanager.BeginTransaction(ctx, func(ctx context.Context, tx txman.Transaction) error {
-		err := tx.Run(ctx, command.StopContainer(currentContainer), command.StartContainer(currentContainer))
+		// current container keeps serving until target is healthy
+		err := startTargetTx(ctx, tx, target, states)
+		if err != nil {
+			return err
+		}
+		err = healthyTx(ctx, tx, fmt.Sprintf("%s-%s", cfg.Service, version))
 		if err != nil {
 			return err
 		}
-		err = tx.Run(ctx, command.StartContainer(newContainer), command.StopContainer(newContainer))
+		// a server that failed a partial deploy may already run the target
+		if running := app.runningVersion(tx.Host()); running != version {
+			currentContainer := fmt.Sprintf("%s-%s", cfg.Service, running)
+			err = tx.Run(ctx, runtime.StopContainer(currentContainer), runtime.StartContainer(currentContainer), txman.WithName("stop current container"))
+			if err != nil {
+				return err
+			}
+		}
+		err = cronTx(ctx, tx, version)
 		if err != nil {
 			return err
 		}
-		err = tx.Do(ctx, WriteToRemoteFile(app.historyFilePath, history), nil)
+		err = tx.Do(ctx, WriteToRemoteFile(app.historyFilePath, historyData, historyFileMode), nil, txman.WithName("record history"))
 		if err != nil {
 			return err
 		}
 
 		return nil
-	})
+	}, txman.WithStepTimeout(cfg.Deploy.StepTimeout), txman.WithProgress(app.progress))
 
 	if err != nil {
 		logging.Info("initiating rollback...")
-		rollbackCtx, rollbackCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		rollbackCtx, rollbackCancel := withTimeout(context.Background(), cfg.Rollback.Timeout)
 		defer rollbackCancel()
-		if err := rollback(rollbackCtx); err != nil {
-			return err
+		if rollbackErr := rollback(rollbackCtx); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
 		}
 
 		// return the original error that caused transaction to fail
 		return err
 	}
 
-	return nil
+	app.setHistory(history)
+
+	hc := hookContext{name: HookPostRollback, version: version, image: imageName(version)}
+	return app.runHooks(ctx, cfg.Hooks.PostRollback, hc)
 }
 
-func (app *App) History(ctx context.Context, sortDir string) ([]HistoryEntry, error) {
+// History returns entries matching filter.
+func (app *App) History(ctx context.Context, filter HistoryFilter) ([]HistoryEntry, error) {
 	if err := app.LoadHistory(ctx); err != nil {
 		return nil, err
 	}
 
-	if sortDir == "asc" {
-		sort.Sort(ByDateAsc(app.history))
-	} else {
-		sort.Sort(ByDateDesc(app.history))
+	history := make([]HistoryEntry, 0, len(app.history))
+	for _, h := range app.history {
+		if filter.match(h) {
+			history = append(history, h)
+		}
+	}
+
+	// limit applies to the most recent entries regardless of sort order
+	sort.Sort(ByDateDesc(history))
+	if filter.Limit > 0 && len(history) > filter.Limit {
+		history = history[:filter.Limit]
+	}
+	if filter.Sort == "asc" {
+		sort.Sort(ByDateAsc(history))
 	}
 
-	return app.history, nil
+	return history, nil
 }
 
 func (app *App) ShowServiceInfo(ctx context.Context) (map[string]string, error) {
@@ -325,38 +574,72 @@ func (app *App) ProxyLogs(ctx context.Context, follow bool, lines int, since str
 }
 
 func (app *App) StopService(ctx context.Context) error {
+	op := app.beginAudit(AuditAppStop, "", "")
+	err := app.stopService(ctx, op)
+	app.finishAudit(ctx, op, err)
+	return err
+}
+
+func (app *App) stopService(ctx context.Context, op *auditOp) error {
 	cfg := config.Get()
 	if err := app.LoadHistory(ctx); err != nil {
 		return err
 	}
+	op.entry.Version = app.LatestVersion()
 	container := fmt.Sprintf("%s-%s", cfg.Service, app.LatestVersion())
-	return app.stopContainer(ctx, container)
+	return app.stopContainer(ctx, op, container)
 }
 
 func (app *App) StopProxy(ctx context.Context) error {
-	container := config.Get().Proxy.Container
-	return app.stopContainer(ctx, container)
+	op := app.beginAudit(AuditProxyStop, "", "")
+	err := app.stopContainer(ctx, op, config.Get().Proxy.Container)
+	app.finishAudit(ctx, op, err)
+	return err
 }
 
 func (app *App) StartService(ctx context.Context) error {
+	op := app.beginAudit(AuditAppStart, "", "")
+	err := app.startService(ctx, op)
+	app.finishAudit(ctx, op, err)
+	return err
+}
+
+func (app *App) startService(ctx context.Context, op *auditOp) error {
 	cfg := config.Get()
 	if err := app.LoadHistory(ctx); err != nil {
 		return err
 	}
+	op.entry.Version = app.LatestVersion()
 	container := fmt.Sprintf("%s-%s", cfg.Service, app.LatestVersion())
-	return app.startContainer(ctx, container)
+	return app.startContainer(ctx, op, container)
 }
 
 func (app *App) StartProxy(ctx context.Context) error {
-	container := config.Get().Proxy.Container
-	return app.startContainer(ctx, container)
+	op := app.beginAudit(AuditProxyStart, "", "")
+	err := app.startContainer(ctx, op, config.Get().Proxy.Container)
+	app.finishAudit(ctx, op, err)
+	return err
 }
 
 func (app *App) RestartService(ctx context.Context) error {
-	if err := app.StopService(ctx); err != nil {
-		return err
+	op := app.beginAudit(AuditAppRestart, "", "")
+	err := app.stopService(ctx, op)
+	if err == nil {
+		err = app.startService(ctx, op)
 	}
-	return app.StartService(ctx)
+	app.finishAudit(ctx, op, err)
+	return err
+}
+
+func (app *App) RestartProxy(ctx context.Context) error {
+	container := config.Get().Proxy.Container
+	op := app.beginAudit(AuditProxyRestart, "", "")
+	err := app.stopContainer(ctx, op, container)
+	if err == nil {
+		err = app.startContainer(ctx, op, container)
+	}
+	app.finishAudit(ctx, op, err)
+	return err
 }
 
 func (app *App) RegistryLogin(ctx context.Context) error {
@@ -367,7 +650,7 @@ func (app *App) RegistryLogin(ctx context.Context) error {
 	password := strings.NewReader(cfg.Registry.Password) // read password from stdin
 
 	return app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
-		err := client.Run(ctx, command.RegistryLogin(registry, username), sshexec.WithStdin(password))
+		err := client.Run(ctx, serverRuntime().RegistryLogin(registry, username), sshexec.WithStdin(password))
 		if err != nil {
 			return fmt.Errorf("failed to login to registry: %s", err)
 		}
@@ -377,7 +660,7 @@ func (app *App) RegistryLogin(ctx context.Context) error {
 
 func (app *App) RegistryLogout(ctx context.Context) error {
 	err := app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
-		err := client.Run(ctx, command.RegistryLogout())
+		err := client.Run(ctx, serverRuntime().RegistryLogout())
 		if err != nil {
 			return fmt.Errorf("failed to logout from registry: %s", err)
 		}
@@ -405,71 +688,168 @@ func (app *App) CreateConfig() error {
 
 func (app *App) RebootProxy(ctx context.Context) error {
 	cfg := config.Get()
-	return app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
-		err := client.Run(ctx, command.StopContainer(cfg.Proxy.Container))
+	runtime := serverRuntime()
+	op := app.beginAudit(AuditProxyReboot, "", "")
+	err := app.txmanager.Execute(ctx, op.track(func(ctx context.Context, client sshexec.Service) error {
+		err := client.Run(ctx, runtime.StopContainer(cfg.Proxy.Container))
 		if err != nil {
 			return err
 		}
 
-		err = client.Run(ctx, command.RemoveContainer(cfg.Proxy.Container))
+		err = client.Run(ctx, runtime.RemoveContainer(cfg.Proxy.Container))
 		if err != nil {
 			return err
 		}
 
-		err = client.Run(ctx, command.RunProxy(cfg.Proxy.Img, cfg.Proxy.Container, cfg.Proxy.Labels, cfg.Proxy.Args))
+		err = client.Run(ctx, runtime.RunProxy(cfg.Proxy.Img, cfg.Proxy.Container, cfg.Proxy.Labels, cfg.Proxy.Args))
 		if err != nil {
 			return err
 		}
 
 		return nil
-	})
+	}))
+	app.finishAudit(ctx, op, err)
+	return err
 }
 
 func (app *App) ExecServiceInteractive(ctx context.Context, execCmd string) error {
-	if err := app.LoadHistory(ctx); err != nil {
+	op := app.beginAudit(AuditAppExec, "", execCmd)
+	err := app.LoadHistory(ctx)
+	if err == nil {
+		op.entry.Version = app.LatestVersion()
+		container := fmt.Sprintf("%s-%s", config.Get().Service, app.LatestVersion())
+		err = app.execInteractive(ctx, op, container, execCmd)
+	}
+	app.finishAudit(ctx, op, err)
+	return err
+}
+
+func (app *App) ExecService(ctx context.Context, execCmd string) (HostOutput, error) {
+	op := app.beginAudit(AuditAppExec, "", execCmd)
+	output := HostOutput{}
+	err := app.LoadHistory(ctx)
+	if err == nil {
+		op.entry.Version = app.LatestVersion()
+		container := fmt.Sprintf("%s-%s", config.Get().Service, app.LatestVersion())
+		output, err = app.exec(ctx, op, container, execCmd)
+	}
+	app.finishAudit(ctx, op, err)
+	return output, err
+}
+
+// RunServiceInteractive runs execCmd in a one-off container of version with a terminal attached.
+// If version is empty, the current version is used.
+func (app *App) RunServiceInteractive(ctx context.Context, version string, execCmd string) error {
+	op := app.beginAudit(AuditAppRun, version, execCmd)
+	err := app.runServiceInteractive(ctx, op, version, execCmd)
+	app.finishAudit(ctx, op, err)
+	return err
+}
+
+func (app *App) runServiceInteractive(ctx context.Context, op *auditOp, version string, execCmd string) error {
+	image, err := app.oneOffImage(ctx, version)
+	if err != nil {
 		return err
 	}
+	op.entry.Version = app.versionOrLatest(version)
 	cfg := config.Get()
-	container := fmt.Sprintf("%s-%s", cfg.Service, app.LatestVersion())
-	return app.execInteractive(ctx, container, execCmd)
+	return app.txmanager.Execute(ctx, op.track(func(ctx context.Context, client sshexec.Service) error {
+		return client.Run(ctx, serverRuntime().RunOneOff(image, cfg.Env, cfg.Volumes, execCmd, true), sshexec.WithPty())
+	}))
 }
 
-func (app *App) ExecService(ctx context.Context, execCmd string) (HostOutput, error) {
-	if err := app.LoadHistory(ctx); err != nil {
+// RunService runs execCmd in a one-off container of version and returns its output.
+// If version is empty, the current version is used.
+func (app *App) RunService(ctx context.Context, version string, execCmd string) (HostOutput, error) {
+	op := app.beginAudit(AuditAppRun, version, execCmd)
+	output, err := app.runService(ctx, op, version, execCmd)
+	app.finishAudit(ctx, op, err)
+	return output, err
+}
+
+func (app *App) runService(ctx context.Context, op *auditOp, version string, execCmd string) (HostOutput, error) {
+	image, err := app.oneOffImage(ctx, version)
+	if err != nil {
 		return HostOutput{}, err
 	}
+	op.entry.Version = app.versionOrLatest(version)
 	cfg := config.Get()
-	container := fmt.Sprintf("%s-%s", cfg.Service, app.LatestVersion())
-	return app.exec(ctx, container, execCmd)
+	var mu sync.Mutex
+	output := HostOutput{}
+	err = app.txmanager.Execute(ctx, op.track(func(ctx context.Context, client sshexec.Service) error {
+		var out bytes.Buffer
+		err := client.Run(ctx, serverRuntime().RunOneOff(image, cfg.Env, cfg.Volumes, execCmd, false), sshexec.WithStdout(&out))
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		output[client.Host()] = out.String()
+		mu.Unlock()
+		return nil
+	}))
+
+	return output, err
+}
+
+func (app *App) versionOrLatest(version string) string {
+	if version == "" {
+		return app.LatestVersion()
+	}
+	return version
+}
+
+func (app *App) oneOffImage(ctx context.Context, version string) (string, error) {
+	if err := app.LoadHistory(ctx); err != nil {
+		return "", err
+	}
+	if version == "" {
+		version = app.LatestVersion()
+	}
+	if version == "" {
+		return "", errors.New("app has not been deployed yet")
+	}
+	if !slices.ContainsFunc(app.history, func(h HistoryEntry) bool { return h.Version == version }) {
+		return "", fmt.Errorf("version %s does not exist", version)
+	}
+	return imageName(version), nil
 }
 
 func (app *App) ExecProxyInteractive(ctx context.Context, execCmd string) error {
-	return app.execInteractive(ctx, config.Get().Proxy.Container, execCmd)
+	op := app.beginAudit(AuditProxyExec, "", execCmd)
+	err := app.execInteractive(ctx, op, config.Get().Proxy.Container, execCmd)
+	app.finishAudit(ctx, op, err)
+	return err
 }
 
 func (app *App) ExecProxy(ctx context.Context, execCmd string) (HostOutput, error) {
-	return app.exec(ctx, config.Get().Proxy.Container, execCmd)
+	op := app.beginAudit(AuditProxyExec, "", execCmd)
+	output, err := app.exec(ctx, op, config.Get().Proxy.Container, execCmd)
+	app.finishAudit(ctx, op, err)
+	return output, err
 }
 
-func (app *App) exec(ctx context.Context, container string, execCmd string) (HostOutput, error) {
+func (app *App) exec(ctx context.Context, op *auditOp, container string, execCmd string) (HostOutput, error) {
+	var mu sync.Mutex
 	output := HostOutput{}
-	err := app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
+	err := app.txmanager.Execute(ctx, op.track(func(ctx context.Context, client sshexec.Service) error {
 		var out bytes.Buffer
-		err := client.Run(ctx, command.Exec(container, execCmd, false), sshexec.WithStdout(&out))
+		err := client.Run(ctx, serverRuntime().Exec(container, execCmd, false), sshexec.WithStdout(&out))
 		if err != nil {
 			return err
 		}
+		mu.Lock()
 		output[client.Host()] = out.String()
+		mu.Unlock()
 		return nil
-	})
+	}))
 
 	return output, err
 }
 
-func (app *App) execInteractive(ctx context.Context, container string, execCmd string) error {
-	return app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
-		return client.Run(ctx, command.Exec(container, execCmd, true), sshexec.WithPty())
-	})
+func (app *App) execInteractive(ctx context.Context, op *auditOp, container string, execCmd string) error {
+	return app.txmanager.Execute(ctx, op.track(func(ctx context.Context, client sshexec.Service) error {
+		return client.Run(ctx, serverRuntime().Exec(container, execCmd, true), sshexec.WithPty())
+	}))
 }
 
 func (app *App) logs(
@@ -494,7 +874,7 @@ func (app *App) logs(
 		sw := stream.New(lineHandler, streamErrHandler)
 		defer sw.Close()
 
-		err := client.Run(ctx, command.ContainerLogs(container, follow, lines, since), sshexec.WithStdout(sw))
+		err := client.Run(ctx, serverRuntime().ContainerLogs(container, follow, lines, since), sshexec.WithStdout(sw))
 		if err != nil {
 			return err
 		}
@@ -508,31 +888,31 @@ func (app *App) logs(
 	return nil
 }
 
-func (app *App) startContainer(ctx context.Context, container string) error {
-	return app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
-		err := client.Run(ctx, command.StartContainer(container))
+func (app *App) startContainer(ctx context.Context, op *auditOp, container string) error {
+	return app.txmanager.Execute(ctx, op.track(func(ctx context.Context, client sshexec.Service) error {
+		err := client.Run(ctx, serverRuntime().StartContainer(container))
 		if err != nil {
 			return fmt.Errorf("failed to start container on %s: %w", client.Host(), err)
 		}
 		return nil
-	})
+	}))
 }
 
-func (app *App) stopContainer(ctx context.Context, container string) error {
-	return app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
-		err := client.Run(ctx, command.StopContainer(container))
+func (app *App) stopContainer(ctx context.Context, op *auditOp, container string) error {
+	return app.txmanager.Execute(ctx, op.track(func(ctx context.Context, client sshexec.Service) error {
+		err := client.Run(ctx, serverRuntime().StopContainer(container))
 		if err != nil {
 			return fmt.Errorf("failed to stop container on %s: %w", client.Host(), err)
 		}
 		return nil
-	})
+	}))
 }
 
 func (app *App) showInfo(ctx context.Context, container string) (map[string]string, error) {
 	output := make(map[string]string)
 	err := app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
 		var stdout bytes.Buffer
-		err := client.Run(ctx, "docker ps --filter name="+container, sshexec.WithStdout(&stdout))
+		err := client.Run(ctx, serverRuntime().Container("ps --filter", "name="+container), sshexec.WithStdout(&stdout), sshexec.WithRetry())
 		if err != nil {
 			return err
 		}
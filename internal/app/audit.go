@@ -0,0 +1,306 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/notify"
+	"github.com/lex-unix/faino/internal/txman"
+)
+
+const (
+	defaultAuditFilePath = "~/.faino/audit.log"
+	// auditFileMode keeps audit logs readable only by their owner
+	auditFileMode = 0600
+)
+
+const (
+	AuditDeploy       = "deploy"
+	AuditRollback     = "rollback"
+	AuditAppStart     = "app start"
+	AuditAppStop      = "app stop"
+	AuditAppRestart   = "app restart"
+	AuditAppExec      = "app exec"
+	AuditAppRun       = "app run"
+	AuditProxyStart   = "proxy start"
+	AuditProxyStop    = "proxy stop"
+	AuditProxyRestart = "proxy restart"
+	AuditProxyReboot  = "proxy reboot"
+	AuditProxyExec    = "proxy exec"
+	AuditRecover      = "recover"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+	// AuditOutcomePartial is a deploy that met its quorum but failed on some servers
+	AuditOutcomePartial = "partial"
+
+	auditHostStatusOK = "ok"
+)
+
+// AuditEntry records a single mutating operation. Entries are stored as JSON lines.
+type AuditEntry struct {
+	ID        string               `json:"id"`
+	Timestamp time.Time            `json:"timestamp"`
+	Operation string               `json:"operation"`
+	Service   string               `json:"service"`
+	Version   string               `json:"version,omitempty"`
+	Command   string               `json:"command,omitempty"`
+	Operator  string               `json:"operator,omitempty"`
+	Commit    string               `json:"commit,omitempty"`
+	Duration  time.Duration        `json:"duration"`
+	Outcome   string               `json:"outcome"`
+	Error     string               `json:"error,omitempty"`
+	Hosts     []notify.HostOutcome `json:"hosts,omitempty"`
+}
+
+// AuditFilter narrows down entries returned by Audit. Zero values match everything.
+type AuditFilter struct {
+	Operation string
+	Since     time.Time
+	Failed    bool
+	Limit     int
+	// Local reads the local audit log instead of logs on servers
+	Local bool
+}
+
+func (f AuditFilter) match(e AuditEntry) bool {
+	if f.Operation != "" && e.Operation != f.Operation {
+		return false
+	}
+	if !f.Since.IsZero() && e.Timestamp.Before(f.Since) {
+		return false
+	}
+	if f.Failed && e.Outcome == AuditOutcomeSuccess {
+		return false
+	}
+	return true
+}
+
+// auditOp collects results of an operation until it is recorded.
+type auditOp struct {
+	entry   AuditEntry
+	started time.Time
+
+	mu    sync.Mutex
+	hosts map[string]error
+}
+
+func (app *App) beginAudit(operation, version, cmd string) *auditOp {
+	return &auditOp{
+		entry: AuditEntry{
+			ID:        generateRandomString(12),
+			Operation: operation,
+			Service:   config.Get().Service,
+			Version:   version,
+			Command:   cmd,
+		},
+		started: time.Now(),
+	}
+}
+
+// track wraps callback to record its result on every host. Operations that
+// run in a transaction are not tracked, their outcome is derived from the error.
+func (o *auditOp) track(callback txman.Callback) txman.Callback {
+	if o == nil {
+		return callback
+	}
+	return func(ctx context.Context, client sshexec.Service) error {
+		err := callback(ctx, client)
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.hosts == nil {
+			o.hosts = make(map[string]error)
+		}
+		o.hosts[client.Host()] = err
+		return err
+	}
+}
+
+func (o *auditOp) hostOutcomes(err error) []notify.HostOutcome {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.hosts == nil {
+		return transactionOutcomes(err, auditHostStatusOK)
+	}
+
+	outcomes := make([]notify.HostOutcome, 0, len(o.hosts))
+	for host, err := range o.hosts {
+		outcome := notify.HostOutcome{Host: host, Status: auditHostStatusOK}
+		if err != nil {
+			outcome.Status = notify.HostStatusFailed
+			outcome.Error = sshexec.RedactCommand(err)
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	slices.SortFunc(outcomes, func(a, b notify.HostOutcome) int {
+		if a.Host < b.Host {
+			return -1
+		}
+		if a.Host > b.Host {
+			return 1
+		}
+		return 0
+	})
+	return outcomes
+}
+
+// finishAudit appends the entry to audit logs on target hosts and to the local
+// audit log if configured. Failures to record are logged, they never fail the operation.
+func (app *App) finishAudit(ctx context.Context, op *auditOp, err error) {
+	git := app.loadGitInfo(ctx)
+	entry := op.entry
+	entry.Timestamp = op.started
+	entry.Duration = time.Since(op.started)
+	entry.Operator = git.operator
+	entry.Commit = git.commit
+	var partialErr *txman.PartialError
+	entry.Outcome = AuditOutcomeSuccess
+	switch {
+	case errors.As(err, &partialErr):
+		entry.Outcome = AuditOutcomePartial
+		entry.Error = sshexec.RedactCommand(err)
+	case err != nil:
+		entry.Outcome = AuditOutcomeFailure
+		entry.Error = sshexec.RedactCommand(err)
+	}
+	entry.Hosts = op.hostOutcomes(err)
+
+	line, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		logging.Warnf("failed to encode audit entry: %s", marshalErr)
+		return
+	}
+
+	// record operations even if they were interrupted
+	ctx = context.WithoutCancel(ctx)
+	_ = app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
+		if err := client.Run(ctx, command.AppendToFile(app.auditFilePath, string(line))); err != nil {
+			logging.WarnHostf(client.Host(), "failed to write audit log: %s", err)
+			return err
+		}
+		return nil
+	})
+
+	if path := config.Get().Audit.Local; path != "" {
+		if err := appendLocalAudit(path, line); err != nil {
+			logging.Warnf("failed to write local audit log: %s", err)
+		}
+	}
+}
+
+func appendLocalAudit(path string, line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, auditFileMode)
+	if err != nil {
+		return err
+	}
+	// logs created before were readable by everyone
+	if err := f.Chmod(auditFileMode); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Audit returns entries matching filter, newest first. Servers keep the same
+// entries, so logs of all hosts are merged and duplicates are dropped.
+func (app *App) Audit(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	var logs [][]byte
+	if filter.Local {
+		path := config.Get().Audit.Local
+		if path == "" {
+			return nil, errors.New("local audit log is not configured, set audit.local")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		logs = append(logs, data)
+	} else {
+		var mu sync.Mutex
+		err := app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
+			data, err := client.ReadFile(ctx, app.auditFilePath)
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read audit log on %s: %w", client.Host(), err)
+			}
+			mu.Lock()
+			logs = append(logs, data)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seen := make(map[string]bool)
+	entries := make([]AuditEntry, 0)
+	for _, data := range logs {
+		parsed, err := parseAuditLog(data)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range parsed {
+			if seen[e.ID] || !filter.match(e) {
+				continue
+			}
+			seen[e.ID] = true
+			entries = append(entries, e)
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b AuditEntry) int { return b.Timestamp.Compare(a.Timestamp) })
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+
+	return entries, nil
+}
+
+// parseAuditLog decodes JSON lines of an audit log. Malformed lines, e.g. left by
+// an interrupted write, are skipped.
+func parseAuditLog(data []byte) ([]AuditEntry, error) {
+	entries := make([]AuditEntry, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e AuditEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			logging.Debugf("skipping malformed audit log line: %s", err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/txman"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAuditLog(t *testing.T) {
+	data := []byte(`{"id":"a","timestamp":"2025-01-01T10:00:00Z","operation":"deploy","outcome":"success"}
+{"id":"b","timestamp":"2025-01-02T10:00:00Z","operation":"app res
+{"id":"c","timestamp":"2025-01-03T10:00:00Z","operation":"rollback","outcome":"failure","error":"boom"}
+
+`)
+
+	entries, err := parseAuditLog(data)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a", entries[0].ID)
+	assert.Equal(t, "c", entries[1].ID)
+	assert.Equal(t, "boom", entries[1].Error)
+}
+
+func TestAuditFilterMatch(t *testing.T) {
+	entry := AuditEntry{
+		Operation: AuditDeploy,
+		Timestamp: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		Outcome:   AuditOutcomeFailure,
+	}
+
+	tests := []struct {
+		name     string
+		filter   AuditFilter
+		expected bool
+	}{
+		{"empty filter", AuditFilter{}, true},
+		{"same operation", AuditFilter{Operation: AuditDeploy}, true},
+		{"other operation", AuditFilter{Operation: AuditRollback}, false},
+		{"newer than since", AuditFilter{Since: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}, true},
+		{"older than since", AuditFilter{Since: time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)}, false},
+		{"failed only", AuditFilter{Failed: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.filter.match(entry))
+		})
+	}
+}
+
+func TestFinishAuditKeepsSecretsOut(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	localLog := filepath.Join(t.TempDir(), "audit.log")
+	useConfig(t, &config.Config{Service: "app", Servers: []string{sshexec.LocalHost}, Audit: config.Audit{Local: localLog}})
+	local, err := sshexec.NewLocal()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".faino"), 0755))
+	a := New(noGit{}, txman.New(local), nil)
+
+	op := a.beginAudit(AuditDeploy, "v2", "")
+	cmdErr := &sshexec.CommandError{Host: sshexec.LocalHost, Command: "docker run --env DB_PASSWORD=hunter2 app:v2", Code: 125}
+	a.finishAudit(context.Background(), op, &txman.HostError{Host: sshexec.LocalHost, Err: cmdErr})
+
+	for _, path := range []string{filepath.Join(home, ".faino", "audit.log"), localLog} {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "hunter2")
+		assert.Contains(t, string(data), "redacted")
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	}
+}
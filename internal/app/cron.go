@@ -0,0 +1,166 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/txman"
+)
+
+const (
+	cronDir         = "~/.faino/cron"
+	cronEnvFileMode = 0600
+)
+
+// cronEnvFile is the env file passed to cron jobs of version. Files are kept per
+// version, so that after rollback the old jobs still find their env.
+func cronEnvFile(service, version string) string {
+	return path.Join(cronDir, fmt.Sprintf("%s-%s.env", service, version))
+}
+
+// cronBackupFile keeps crontab of the host from before the last switch, so that
+// an interrupted deploy can be undone by `faino recover`.
+func cronBackupFile(service string) string {
+	return path.Join(cronDir, fmt.Sprintf("%s.crontab", service))
+}
+
+func cronBlockMarkers(service string) (string, string) {
+	return fmt.Sprintf("# BEGIN faino %s", service), fmt.Sprintf("# END faino %s", service)
+}
+
+// cronBlock renders crontab lines of jobs running version of the service.
+func cronBlock(service, version string, jobs []config.CronJob, volumes []string) string {
+	if len(jobs) == 0 {
+		return ""
+	}
+
+	begin, end := cronBlockMarkers(service)
+	img := imageName(version)
+	envFile := cronEnvFile(service, version)
+
+	var sb strings.Builder
+	sb.WriteString(begin + "\n")
+	sb.WriteString("# managed by faino, do not edit\n")
+	for _, job := range jobs {
+		cmd := serverRuntime().RunCronJob(img, envFile, volumes, job.Name, job.Command)
+		// % is a newline in crontab unless escaped
+		cmd = strings.ReplaceAll(cmd, "%", `\%`)
+		fmt.Fprintf(&sb, "%s %s\n", job.Schedule, cmd)
+	}
+	sb.WriteString(end + "\n")
+
+	return sb.String()
+}
+
+// replaceCronBlock replaces block of the service in crontab with block.
+// If there was no block, it is appended. Empty block removes it.
+func replaceCronBlock(crontab, service, block string) string {
+	begin, end := cronBlockMarkers(service)
+	lines := strings.SplitAfter(crontab, "\n")
+
+	var sb strings.Builder
+	inBlock := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == begin:
+			inBlock = true
+		case trimmed == end:
+			inBlock = false
+		case !inBlock && line != "":
+			sb.WriteString(line)
+		}
+	}
+
+	result := sb.String()
+	if result != "" && !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	return result + block
+}
+
+// envFileContents renders env in docker --env-file format.
+func envFileContents(env map[string]string) []byte {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s=%s\n", k, env[k])
+	}
+	return buf.Bytes()
+}
+
+func readCrontab(ctx context.Context, client sshexec.Service) (string, error) {
+	var out bytes.Buffer
+	err := client.Run(ctx, command.ReadCrontab(), sshexec.WithStdout(&out), sshexec.WithRetry())
+	if err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func installCrontab(ctx context.Context, client sshexec.Service, crontab string) error {
+	return client.Run(ctx, command.InstallCrontab(), sshexec.WithStdin(strings.NewReader(crontab)))
+}
+
+// cronTx switches cron jobs to version as a transaction step. Jobs are installed on
+// cron hosts and removed from the others. On rollback, previous crontab is restored.
+// It is also backed up on the host, so that the rollback can be replayed from the journal.
+func cronTx(ctx context.Context, tx txman.Transaction, version string) error {
+	cfg := config.Get()
+	backup := cronBackupFile(cfg.Service)
+
+	var previous string
+	changed := false
+	return tx.Do(ctx, func(ctx context.Context, client sshexec.Service) error {
+		crontab, err := readCrontab(ctx, client)
+		if err != nil {
+			return err
+		}
+		if err := client.MkdirAll(ctx, cronDir); err != nil {
+			return err
+		}
+		if err := client.WriteFile(ctx, backup, []byte(crontab), cronEnvFileMode); err != nil {
+			return err
+		}
+
+		var block string
+		if slices.Contains(cfg.Cron.Hosts, client.Host()) {
+			block = cronBlock(cfg.Service, version, cfg.Cron.Jobs, cfg.Volumes)
+		}
+		updated := replaceCronBlock(crontab, cfg.Service, block)
+		if updated == crontab {
+			return nil
+		}
+
+		if block != "" {
+			if err := client.WriteFile(ctx, cronEnvFile(cfg.Service, version), envFileContents(cfg.Env), cronEnvFileMode); err != nil {
+				return err
+			}
+		}
+		if err := installCrontab(ctx, client, updated); err != nil {
+			return err
+		}
+
+		previous = crontab
+		changed = true
+		return nil
+	}, func(ctx context.Context, client sshexec.Service) error {
+		if !changed {
+			return nil
+		}
+		return installCrontab(ctx, client, previous)
+	}, txman.WithName("install cron jobs"), txman.WithRollbackCommand(command.InstallCrontabFile(backup)))
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceCronBlock(t *testing.T) {
+	block := "# BEGIN faino web\n0 3 * * * new\n# END faino web\n"
+
+	tests := []struct {
+		name     string
+		crontab  string
+		block    string
+		expected string
+	}{
+		{
+			name:     "empty crontab",
+			crontab:  "",
+			block:    block,
+			expected: block,
+		},
+		{
+			name:     "keeps unmanaged lines",
+			crontab:  "MAILTO=ops@example.com\n* * * * * backup",
+			block:    block,
+			expected: "MAILTO=ops@example.com\n* * * * * backup\n" + block,
+		},
+		{
+			name:     "replaces existing block",
+			crontab:  "* * * * * backup\n# BEGIN faino web\n0 3 * * * old\n# END faino web\n",
+			block:    block,
+			expected: "* * * * * backup\n" + block,
+		},
+		{
+			name:     "removes block",
+			crontab:  "# BEGIN faino web\n0 3 * * * old\n# END faino web\n* * * * * backup\n",
+			block:    "",
+			expected: "* * * * * backup\n",
+		},
+		{
+			name:     "keeps blocks of other services",
+			crontab:  "# BEGIN faino api\n0 3 * * * api\n# END faino api\n",
+			block:    block,
+			expected: "# BEGIN faino api\n0 3 * * * api\n# END faino api\n" + block,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, replaceCronBlock(tt.crontab, "web", tt.block))
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/txman"
+)
+
+const filesBackupDir = "~/.faino/files"
+
+// fileBackupPath is where the previous contents of the i-th uploaded file are kept
+// on the host, so that an interrupted deploy can be undone by `faino recover`.
+func fileBackupPath(service string, i int) string {
+	return path.Join(filesBackupDir, service, strconv.Itoa(i))
+}
+
+// fileUpload is a single local file read into memory and ready to be written on hosts.
+type fileUpload struct {
+	local  string
+	remote string
+	mode   os.FileMode
+	data   []byte
+}
+
+// remoteFileBackup holds contents of a remote file before it was overwritten.
+// If existed is false, the file was created by the upload.
+type remoteFileBackup struct {
+	remote  string
+	mode    os.FileMode
+	data    []byte
+	existed bool
+}
+
+// collectFiles reads files from the `files` config section. Directories are
+// walked recursively and mirrored under the remote path.
+func collectFiles(files []config.File) ([]fileUpload, error) {
+	var uploads []fileUpload
+	for _, f := range files {
+		var mode os.FileMode
+		if f.Mode != "" {
+			m, err := config.ParseFileMode(f.Mode)
+			if err != nil {
+				return nil, err
+			}
+			mode = m
+		}
+
+		err := filepath.WalkDir(f.Local, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(f.Local, p)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+
+			remote := f.Remote
+			if rel != "." {
+				remote = path.Join(f.Remote, filepath.ToSlash(rel))
+			}
+			perm := mode
+			if perm == 0 {
+				perm = info.Mode().Perm()
+			}
+
+			uploads = append(uploads, fileUpload{local: p, remote: remote, mode: perm, data: data})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", f.Local, err)
+		}
+	}
+
+	return uploads, nil
+}
+
+// uploadFiles writes uploads on the host. If backups is not nil, previous contents
+// of every overwritten file are appended to it so they can be restored.
+func uploadFiles(ctx context.Context, client sshexec.Service, uploads []fileUpload, backups *[]remoteFileBackup) error {
+	for _, u := range uploads {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if backups != nil {
+			backup := remoteFileBackup{remote: u.remote}
+			if info, err := client.Stat(ctx, u.remote); err == nil {
+				data, err := client.ReadFile(ctx, u.remote)
+				if err != nil {
+					return err
+				}
+				backup.existed = true
+				backup.mode = info.Mode().Perm()
+				backup.data = data
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			*backups = append(*backups, backup)
+		}
+
+		if err := client.MkdirAll(ctx, path.Dir(u.remote)); err != nil {
+			return err
+		}
+		logging.InfoHostf(client.Host(), "uploading %s to %s", u.local, u.remote)
+		if err := client.WriteFile(ctx, u.remote, u.data, u.mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreFiles reverts files written by uploadFiles in reverse order.
+func restoreFiles(ctx context.Context, client sshexec.Service, backups []remoteFileBackup) error {
+	var err error
+	for i := len(backups) - 1; i >= 0; i-- {
+		b := backups[i]
+		if b.existed {
+			err = errors.Join(err, client.WriteFile(ctx, b.remote, b.data, b.mode))
+		} else if removeErr := client.Remove(ctx, b.remote); !errors.Is(removeErr, os.ErrNotExist) {
+			// the upload may have failed before the file was created
+			err = errors.Join(err, removeErr)
+		}
+	}
+	return err
+}
+
+// PushFiles uploads files from the `files` config section to every host.
+func (app *App) PushFiles(ctx context.Context) error {
+	uploads, err := collectFiles(config.Get().Files)
+	if err != nil {
+		return err
+	}
+	if len(uploads) == 0 {
+		return nil
+	}
+
+	return app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
+		return uploadFiles(ctx, client, uploads, nil)
+	})
+}
+
+// pushFilesTx uploads files as a transaction step. On rollback, overwritten
+// files get their previous contents back and new files are removed. If the
+// upload fails partway, files written so far are restored right away, since
+// the rollback of a failed step is never run. Previous contents are also kept
+// on the host, so that the rollback can be replayed from the journal.
+func pushFilesTx(ctx context.Context, tx txman.Transaction, uploads []fileUpload) error {
+	if len(uploads) == 0 {
+		return nil
+	}
+
+	service := config.Get().Service
+	restoreCmds := make([]string, 0, len(uploads))
+	for i := len(uploads) - 1; i >= 0; i-- {
+		restoreCmds = append(restoreCmds, command.RestoreFile(fileBackupPath(service, i), uploads[i].remote))
+	}
+
+	var backups []remoteFileBackup
+	return tx.Do(ctx, func(ctx context.Context, client sshexec.Service) error {
+		// backups of the previous deploy must not be restored
+		if err := client.Run(ctx, command.RemoveDir(path.Join(filesBackupDir, service))); err != nil {
+			return err
+		}
+		// files are restored even if the step timed out
+		restoreCtx := context.WithoutCancel(ctx)
+		if err := uploadFiles(ctx, client, uploads, &backups); err != nil {
+			return errors.Join(err, restoreFiles(restoreCtx, client, backups))
+		}
+		if err := writeFileBackups(ctx, client, service, backups); err != nil {
+			return errors.Join(err, restoreFiles(restoreCtx, client, backups))
+		}
+		return nil
+	}, func(ctx context.Context, client sshexec.Service) error {
+		return restoreFiles(ctx, client, backups)
+	}, txman.WithName("upload files"), txman.WithRollbackCommand(strings.Join(restoreCmds, " && ")))
+}
+
+// writeFileBackups writes previous contents of overwritten files on the host,
+// new files have no backup.
+func writeFileBackups(ctx context.Context, client sshexec.Service, service string, backups []remoteFileBackup) error {
+	if err := client.MkdirAll(ctx, path.Join(filesBackupDir, service)); err != nil {
+		return err
+	}
+	for i, b := range backups {
+		if !b.existed {
+			continue
+		}
+		if err := client.WriteFile(ctx, fileBackupPath(service, i), b.data, b.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/txman"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useConfig makes cfg the loaded config for the duration of the test.
+func useConfig(t *testing.T, cfg *config.Config) {
+	prev := config.Get()
+	config.Set(cfg)
+	t.Cleanup(func() { config.Set(prev) })
+}
+
+func TestPushFilesTxRestoresPartialUpload(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	useConfig(t, &config.Config{Service: "app"})
+	local, err := sshexec.NewLocal()
+	require.NoError(t, err)
+
+	existing := filepath.Join(home, "existing.conf")
+	require.NoError(t, os.WriteFile(existing, []byte("old"), 0644))
+	// a regular file where a directory is expected makes the last upload fail
+	require.NoError(t, os.WriteFile(filepath.Join(home, "blocked"), nil, 0644))
+	uploads := []fileUpload{
+		{local: "existing.conf", remote: existing, mode: 0644, data: []byte("new")},
+		{local: "created.conf", remote: filepath.Join(home, "created.conf"), mode: 0644, data: []byte("new")},
+		{local: "failing.conf", remote: filepath.Join(home, "blocked", "failing.conf"), mode: 0644, data: []byte("new")},
+	}
+
+	_, err = txman.New(local).BeginTransaction(context.Background(), func(ctx context.Context, tx txman.Transaction) error {
+		return pushFilesTx(ctx, tx, uploads)
+	})
+
+	assert.Error(t, err)
+	data, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+	assert.NoFileExists(t, filepath.Join(home, "created.conf"))
+}
+
+func TestPushFilesTxRecover(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	useConfig(t, &config.Config{Service: "app"})
+	local, err := sshexec.NewLocal()
+	require.NoError(t, err)
+
+	existing := filepath.Join(home, "existing.conf")
+	require.NoError(t, os.WriteFile(existing, []byte("old"), 0600))
+	uploads := []fileUpload{
+		{local: "existing.conf", remote: existing, mode: 0644, data: []byte("new")},
+		{local: "created.conf", remote: filepath.Join(home, "created.conf"), mode: 0644, data: []byte("new")},
+	}
+	m := txman.New(local)
+	m.EnableJournal(txman.DefaultJournalDir)
+
+	// the process dies before the rollback, which leaves the journal behind
+	_, err = m.BeginTransaction(context.Background(), func(ctx context.Context, tx txman.Transaction) error {
+		if err := pushFilesTx(ctx, tx, uploads); err != nil {
+			return err
+		}
+		return errors.New("deploy failed")
+	})
+	require.Error(t, err)
+	journals, err := m.Pending(context.Background())
+	require.NoError(t, err)
+	require.Len(t, journals, 1)
+
+	require.NoError(t, m.Recover(context.Background(), journals[0].ID, false))
+
+	data, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+	info, err := os.Stat(existing)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assert.NoFileExists(t, filepath.Join(home, "created.conf"))
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/txman"
+)
+
+func healthCheckCommand(container string, hc config.HealthCheck) string {
+	if hc.Command != "" {
+		return serverRuntime().HealthCheckCommand(container, hc.Command)
+	}
+	return serverRuntime().HealthCheckHTTP(container, hc.Port, hc.Path)
+}
+
+// waitHealthy runs health check of container until it passes or hc.Timeout elapses.
+func waitHealthy(ctx context.Context, client sshexec.Service, container string, hc config.HealthCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, hc.Timeout)
+	defer cancel()
+
+	cmd := healthCheckCommand(container, hc)
+	logging.InfoHostf(client.Host(), "waiting for %s to become healthy", container)
+	for {
+		err := client.Run(ctx, cmd)
+		// a check cut short by the timeout did not pass, even if it exited cleanly
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err == nil {
+			logging.InfoHostf(client.Host(), "%s is healthy", container)
+			return nil
+		}
+		logging.Debugf("health check of %s on %s failed: %s", container, client.Host(), err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s did not become healthy in %s: %w", container, hc.Timeout, err)
+		case <-time.After(hc.Interval):
+		}
+	}
+}
+
+// healthyTx waits for container to pass health check and then for the proxy to
+// drain traffic to both versions. Without a configured check it only drains.
+func healthyTx(ctx context.Context, tx txman.Transaction, container string) error {
+	hc := config.Get().HealthCheck
+	return tx.Do(ctx, func(ctx context.Context, client sshexec.Service) error {
+		if hc.Enabled() {
+			if err := waitHealthy(ctx, client, container, hc); err != nil {
+				return err
+			}
+		}
+		if hc.Drain > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(hc.Drain):
+			}
+		}
+		return nil
+	}, nil, txman.WithName("wait for health check"), txman.WithTimeout(0)) // bounded by timeout of the health check
+}
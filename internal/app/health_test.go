@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/txman"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkStub answers health checks with run and counts them.
+type checkStub struct {
+	sshexec.Service
+	calls atomic.Int32
+	run   func(ctx context.Context, call int32) error
+}
+
+func (c *checkStub) Host() string {
+	return "host1"
+}
+
+func (c *checkStub) Run(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+	return c.run(ctx, c.calls.Add(1))
+}
+
+func TestWaitHealthy(t *testing.T) {
+	useConfig(t, &config.Config{})
+	hc := config.HealthCheck{Path: "/up", Port: 3000, Interval: 10 * time.Millisecond, Timeout: 200 * time.Millisecond}
+
+	t.Run("retries until the check passes", func(t *testing.T) {
+		client := &checkStub{run: func(ctx context.Context, call int32) error {
+			if call < 3 {
+				return errors.New("connection refused")
+			}
+			return nil
+		}}
+
+		err := waitHealthy(context.Background(), client, "app-v2", hc)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), client.calls.Load())
+	})
+
+	t.Run("fails after timeout", func(t *testing.T) {
+		client := &checkStub{run: func(ctx context.Context, call int32) error {
+			return errors.New("connection refused")
+		}}
+
+		err := waitHealthy(context.Background(), client, "app-v2", hc)
+
+		assert.ErrorContains(t, err, "app-v2 did not become healthy in 200ms")
+		assert.Greater(t, client.calls.Load(), int32(1))
+	})
+
+	t.Run("check that hangs past timeout is not healthy", func(t *testing.T) {
+		// like a command that exits cleanly on SIGTERM
+		client := &checkStub{run: func(ctx context.Context, call int32) error {
+			<-ctx.Done()
+			return nil
+		}}
+
+		err := waitHealthy(context.Background(), client, "app-v2", hc)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestHealthyTxDrains(t *testing.T) {
+	useConfig(t, &config.Config{HealthCheck: config.HealthCheck{
+		Command:  "true",
+		Interval: 10 * time.Millisecond,
+		Timeout:  time.Second,
+		Drain:    100 * time.Millisecond,
+	}})
+	client := &checkStub{run: func(ctx context.Context, call int32) error { return nil }}
+
+	started := time.Now()
+	_, err := txman.New(client).BeginTransaction(context.Background(), func(ctx context.Context, tx txman.Transaction) error {
+		return healthyTx(ctx, tx, "app-v2")
+	})
+
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(started), 100*time.Millisecond)
+	assert.Equal(t, int32(1), client.calls.Load())
+}
@@ -4,22 +4,74 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sort"
 	"time"
 
 	"github.com/lex-unix/faino/internal/config"
-	"github.com/lex-unix/faino/internal/exec/sshexec"
 	"github.com/lex-unix/faino/internal/logging"
-	"github.com/lex-unix/faino/internal/txman"
 )
 
 const (
 	defautlHistoryFilePath = "~/.faino/history.json"
+	historyFileMode        = 0644
 )
 
+const (
+	// HistoryStatusActive marks the version currently running on servers.
+	HistoryStatusActive = "active"
+	// HistoryStatusInactive marks a version replaced by a newer deploy.
+	HistoryStatusInactive = "inactive"
+	// HistoryStatusRolledBack marks a version replaced by a rollback.
+	HistoryStatusRolledBack = "rolled-back"
+	// HistoryStatusFailed marks a version whose deploy failed and was rolled back.
+	HistoryStatusFailed = "failed"
+)
+
+var HistoryStatuses = []string{HistoryStatusActive, HistoryStatusInactive, HistoryStatusRolledBack, HistoryStatusFailed}
+
 type HistoryEntry struct {
-	Version   string    `json:"version"`
-	Timestamp time.Time `json:"timestamp"`
+	Version  string `json:"version"`
+	Image    string `json:"image,omitempty"`
+	Deployer string `json:"deployer,omitempty"`
+	Commit   string `json:"commit,omitempty"`
+	// Volumes the container was started with, used to recreate it on rollback
+	Volumes []string `json:"volumes,omitempty"`
+	// DeployedAt is when the version was first deployed, it never changes
+	DeployedAt time.Time `json:"deployed_at"`
+	// ActivatedAt is when the version last became active, by deploy or rollback
+	ActivatedAt time.Time `json:"activated_at,omitzero"`
+	Status      string    `json:"status"`
+	Notes       string    `json:"notes,omitempty"`
+	// FailedHosts are servers the version failed to deploy on and that kept
+	// the previous version, because the deploy met its quorum on the others.
+	FailedHosts []string `json:"failed_hosts,omitempty"`
+	// Kept maps each of FailedHosts to the version it kept running.
+	Kept map[string]string `json:"kept,omitempty"`
+}
+
+// Partial reports whether the version was deployed only on some servers.
+func (e HistoryEntry) Partial() bool {
+	return len(e.FailedHosts) > 0
+}
+
+// HistoryFilter narrows down entries returned by History. Zero values match everything.
+type HistoryFilter struct {
+	Since  time.Time
+	Status string
+	Limit  int
+	// Sort is either "asc" or "desc" by deploy time
+	Sort string
+}
+
+func (f HistoryFilter) match(h HistoryEntry) bool {
+	if !f.Since.IsZero() && h.DeployedAt.Before(f.Since) {
+		return false
+	}
+	if f.Status != "" && h.Status != f.Status {
+		return false
+	}
+	return true
 }
 
 // ByDateAsc is a helper type for History slice that implements sort.Interface
@@ -27,14 +79,14 @@ type ByDateAsc []HistoryEntry
 
 func (a ByDateAsc) Len() int           { return len(a) }
 func (a ByDateAsc) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ByDateAsc) Less(i, j int) bool { return a[i].Timestamp.Before(a[j].Timestamp) }
+func (a ByDateAsc) Less(i, j int) bool { return a[i].DeployedAt.Before(a[j].DeployedAt) }
 
 // ByDateDesc is a helper type for History slice that implements sort.Interface
 type ByDateDesc []HistoryEntry
 
 func (a ByDateDesc) Len() int           { return len(a) }
 func (a ByDateDesc) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a ByDateDesc) Less(i, j int) bool { return a[i].Timestamp.After(a[j].Timestamp) }
+func (a ByDateDesc) Less(i, j int) bool { return a[i].DeployedAt.After(a[j].DeployedAt) }
 
 func (app *App) LoadHistory(ctx context.Context) error {
 	if app.history != nil {
@@ -77,16 +129,57 @@ func (app *App) LoadHistory(ctx context.Context) error {
 }
 
 func (app *App) loadHistory(raw []byte) error {
-	var h []HistoryEntry
-	err := json.Unmarshal(raw, &h)
+	h, err := parseHistory(raw)
 	if err != nil {
-		return fmt.Errorf("corrupted history file: %w", err)
+		return err
 	}
 	app.history = h
+	app.historySorted = false
 	app.sortHistory()
 	return nil
 }
 
+// legacyHistoryEntry is the entry format before deploy metadata was added.
+// Rollback used to overwrite timestamp, so it is when the version was last activated.
+type legacyHistoryEntry struct {
+	HistoryEntry
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// parseHistory decodes history file and migrates entries of the legacy format.
+// Migrated entries are persisted by the next write of history.
+func parseHistory(raw []byte) ([]HistoryEntry, error) {
+	var entries []legacyHistoryEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("corrupted history file: %w", err)
+	}
+
+	h := make([]HistoryEntry, 0, len(entries))
+	legacy := false
+	for _, e := range entries {
+		if e.Status == "" {
+			legacy = true
+			e.DeployedAt = e.Timestamp
+			e.ActivatedAt = e.Timestamp
+			e.Status = HistoryStatusInactive
+		}
+		h = append(h, e.HistoryEntry)
+	}
+
+	// the most recently activated legacy version is the one running
+	if legacy && !slices.ContainsFunc(h, func(e HistoryEntry) bool { return e.Status == HistoryStatusActive }) && len(h) > 0 {
+		latest := 0
+		for i := range h {
+			if h[i].ActivatedAt.After(h[latest].ActivatedAt) {
+				latest = i
+			}
+		}
+		h[latest].Status = HistoryStatusActive
+	}
+
+	return h, nil
+}
+
 // sortHistory sorts history in descending order and modifies history slice.
 // If history is empty or already sorted it does nothing.
 func (app *App) sortHistory() {
@@ -98,30 +191,77 @@ func (app *App) sortHistory() {
 	app.historySorted = true
 }
 
-func (app *App) AppendVersion(version string) txman.Callback {
-	h := HistoryEntry{
-		Version:   version,
-		Timestamp: time.Now(),
+// historyWith returns a copy of history with entry added. Version that was active
+// before gets status previous if entry becomes active.
+func (app *App) historyWith(entry HistoryEntry, previous string) []HistoryEntry {
+	history := make([]HistoryEntry, 0, len(app.history)+1)
+	for _, h := range app.history {
+		if entry.Status == HistoryStatusActive && h.Status == HistoryStatusActive {
+			h.Status = previous
+		}
+		history = append(history, h)
 	}
-	app.history = append(app.history, h)
-	app.historySorted = false
-	data, marshalErr := json.Marshal(app.history)
+	return append(history, entry)
+}
 
-	return func(ctx context.Context, client sshexec.Service) error {
-		if marshalErr != nil {
-			return fmt.Errorf("failed to marshal history: %w", marshalErr)
+// historyWithActive returns a copy of history where version becomes active. Version
+// that was active before gets status previous.
+func (app *App) historyWithActive(version string, previous string) []HistoryEntry {
+	history := slices.Clone(app.history)
+	for i := range history {
+		switch {
+		case history[i].Version == version:
+			history[i].Status = HistoryStatusActive
+			history[i].ActivatedAt = time.Now()
+			// rollback starts version on every server
+			history[i].FailedHosts = nil
+			history[i].Kept = nil
+		case history[i].Status == HistoryStatusActive:
+			history[i].Status = previous
 		}
-		return client.WriteFile(app.historyFilePath, data)
 	}
+	return history
 }
 
-func (app *App) LatestVersion() string {
+// runningVersion returns the version running on host. It is the active version,
+// unless host failed the deploy of it and kept running an older one.
+func (app *App) runningVersion(host string) string {
 	app.sortHistory()
-	if app.history == nil {
-		return ""
+	for _, h := range app.history {
+		if h.Status != HistoryStatusActive {
+			continue
+		}
+		if v, ok := h.Kept[host]; ok {
+			return v
+		}
+		return h.Version
 	}
-	if len(app.history) == 0 {
-		return ""
+	return ""
+}
+
+// keptVersions maps hosts to versions they run, for hosts that failed a deploy
+// and were rolled back to the version they had.
+func (app *App) keptVersions(hosts []string) map[string]string {
+	kept := make(map[string]string, len(hosts))
+	for _, host := range hosts {
+		kept[host] = app.runningVersion(host)
+	}
+	return kept
+}
+
+// setHistory replaces history kept in memory after it was written to hosts.
+func (app *App) setHistory(history []HistoryEntry) {
+	app.history = history
+	app.historySorted = false
+}
+
+// LatestVersion returns the active version, or an empty string if app was never deployed.
+func (app *App) LatestVersion() string {
+	app.sortHistory()
+	for _, h := range app.history {
+		if h.Status == HistoryStatusActive {
+			return h.Version
+		}
 	}
-	return app.history[0].Version
+	return ""
 }
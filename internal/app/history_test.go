@@ -0,0 +1,117 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHistoryMigratesLegacyEntries(t *testing.T) {
+	raw := []byte(`[
+		{"version": "v1", "timestamp": "2025-01-01T10:00:00Z"},
+		{"version": "v2", "timestamp": "2025-01-03T10:00:00Z"},
+		{"version": "v3", "timestamp": "2025-01-02T10:00:00Z"}
+	]`)
+
+	history, err := parseHistory(raw)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+
+	assert.Equal(t, time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC), history[0].DeployedAt.UTC())
+	assert.Equal(t, history[0].DeployedAt, history[0].ActivatedAt)
+	assert.Equal(t, HistoryStatusInactive, history[0].Status)
+	assert.Equal(t, HistoryStatusActive, history[1].Status)
+	assert.Equal(t, HistoryStatusInactive, history[2].Status)
+}
+
+func TestParseHistoryKeepsCurrentEntries(t *testing.T) {
+	raw := []byte(`[
+		{"version": "v1", "deployed_at": "2025-01-01T10:00:00Z", "status": "rolled-back"},
+		{"version": "v2", "deployed_at": "2025-01-02T10:00:00Z", "status": "failed"}
+	]`)
+
+	history, err := parseHistory(raw)
+	require.NoError(t, err)
+	assert.Equal(t, HistoryStatusRolledBack, history[0].Status)
+	assert.Equal(t, HistoryStatusFailed, history[1].Status)
+}
+
+func TestHistoryWithActive(t *testing.T) {
+	deployed := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	app := &App{history: []HistoryEntry{
+		{Version: "v2", DeployedAt: deployed.Add(time.Hour), Status: HistoryStatusActive},
+		{Version: "v1", DeployedAt: deployed, Status: HistoryStatusInactive},
+	}}
+
+	history := app.historyWithActive("v1", HistoryStatusRolledBack)
+
+	assert.Equal(t, HistoryStatusRolledBack, history[0].Status)
+	assert.Equal(t, HistoryStatusActive, history[1].Status)
+	assert.Equal(t, deployed, history[1].DeployedAt)
+	assert.False(t, history[1].ActivatedAt.IsZero())
+	// history in memory is unchanged until written to hosts
+	assert.Equal(t, HistoryStatusActive, app.history[0].Status)
+}
+
+func TestRunningVersionAfterPartialDeploy(t *testing.T) {
+	deployed := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	app := &App{history: []HistoryEntry{
+		{Version: "v1", DeployedAt: deployed, Status: HistoryStatusActive},
+	}}
+
+	// v2 fails on host2, which is rolled back to v1
+	v2 := HistoryEntry{Version: "v2", DeployedAt: deployed.Add(time.Hour), Status: HistoryStatusActive, FailedHosts: []string{"host2"}}
+	v2.Kept = app.keptVersions(v2.FailedHosts)
+	app.setHistory(app.historyWith(v2, HistoryStatusInactive))
+
+	assert.Equal(t, "v2", app.runningVersion("host1"))
+	assert.Equal(t, "v1", app.runningVersion("host2"))
+
+	// v3 fails on host2 again, which still runs v1
+	v3 := HistoryEntry{Version: "v3", DeployedAt: deployed.Add(2 * time.Hour), Status: HistoryStatusActive, FailedHosts: []string{"host2"}}
+	v3.Kept = app.keptVersions(v3.FailedHosts)
+	app.setHistory(app.historyWith(v3, HistoryStatusInactive))
+
+	assert.Equal(t, "v3", app.runningVersion("host1"))
+	assert.Equal(t, "v1", app.runningVersion("host2"))
+
+	// rollback starts the version on every server
+	app.setHistory(app.historyWithActive("v2", HistoryStatusRolledBack))
+	assert.Equal(t, "v2", app.runningVersion("host2"))
+}
+
+func TestPreviousVersion(t *testing.T) {
+	activated := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	history := []HistoryEntry{
+		{Version: "v4", Status: HistoryStatusFailed},
+		{Version: "v3", Status: HistoryStatusActive, ActivatedAt: activated.Add(3 * time.Hour)},
+		{Version: "v2", Status: HistoryStatusInactive, ActivatedAt: activated.Add(time.Hour)},
+		{Version: "v1", Status: HistoryStatusRolledBack, ActivatedAt: activated.Add(2 * time.Hour)},
+	}
+
+	tests := []struct {
+		name     string
+		steps    int
+		expected string
+		err      bool
+	}{
+		{name: "previous", steps: 1, expected: "v1"},
+		{name: "two steps", steps: 2, expected: "v2"},
+		{name: "too many steps", steps: 3, err: true},
+		{name: "zero steps", steps: 0, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := previousVersion(history, tt.steps)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got.Version)
+		})
+	}
+}
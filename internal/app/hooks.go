@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/localexec"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/txman"
+)
+
+const (
+	HookPreBuild     = "pre-build"
+	HookPreDeploy    = "pre-deploy"
+	HookPostDeploy   = "post-deploy"
+	HookPostRollback = "post-rollback"
+)
+
+// hookContext is exposed to hooks as FAINO_* environment variables.
+type hookContext struct {
+	name    string
+	version string
+	image   string
+}
+
+func (h hookContext) env() map[string]string {
+	cfg := config.Get()
+	return map[string]string{
+		"FAINO_HOOK":    h.name,
+		"FAINO_SERVICE": cfg.Service,
+		"FAINO_VERSION": h.version,
+		"FAINO_IMAGE":   h.image,
+		"FAINO_HOSTS":   strings.Join(targetHosts(), ","),
+	}
+}
+
+// targetHosts returns hosts the current command runs on.
+func targetHosts() []string {
+	cfg := config.Get()
+	if cfg.Host != "" {
+		return []string{cfg.Host}
+	}
+	return cfg.Servers
+}
+
+// onceHook runs a hook a single time even though its callback is executed on every host.
+// Hosts arriving later wait for the first run to finish and share its result.
+type onceHook struct {
+	once sync.Once
+	err  error
+}
+
+func (o *onceHook) do(fn func() error) error {
+	o.once.Do(func() {
+		o.err = fn()
+	})
+	return o.err
+}
+
+// hookCallback returns callback that runs hook on hosts. Local hooks and remote
+// hooks with `once` are run only once, every host waits for them to finish.
+func (app *App) hookCallback(hook config.Hook, hc hookContext) txman.Callback {
+	var o onceHook
+	return func(ctx context.Context, client sshexec.Service) error {
+		switch {
+		case !hook.Remote:
+			return o.do(func() error { return app.runLocalHook(ctx, hook, hc) })
+		case hook.Once:
+			return o.do(func() error { return runRemoteHook(ctx, client, hook, hc) })
+		default:
+			return runRemoteHook(ctx, client, hook, hc)
+		}
+	}
+}
+
+// runLocalHook runs hook on the local machine.
+func (app *App) runLocalHook(ctx context.Context, hook config.Hook, hc hookContext) error {
+	env := make([]string, 0)
+	for k, v := range hc.env() {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	logging.Infof("running %s hook %q", hc.name, hook.Command)
+	if err := app.lexec.Run(ctx, hook.Command, localexec.WithEnv(env)); err != nil {
+		return fmt.Errorf("%s hook %q failed: %w", hc.name, hook.Command, err)
+	}
+	return nil
+}
+
+// runRemoteHook runs hook in a one-off container of hc.image on the host.
+func runRemoteHook(ctx context.Context, client sshexec.Service, hook config.Hook, hc hookContext) error {
+	cfg := config.Get()
+	env := maps.Clone(cfg.Env)
+	if env == nil {
+		env = make(map[string]string)
+	}
+	maps.Copy(env, hc.env())
+
+	logging.InfoHostf(client.Host(), "running %s hook %q", hc.name, hook.Command)
+	err := client.Run(ctx, serverRuntime().RunOneOff(hc.image, env, cfg.Volumes, hook.Command, false))
+	if err != nil {
+		return fmt.Errorf("%s hook %q failed on %s: %w", hc.name, hook.Command, client.Host(), err)
+	}
+	return nil
+}
+
+// runHooksTx runs hooks of stage name as transaction steps, so a failing hook rolls back the transaction.
+func (app *App) runHooksTx(ctx context.Context, tx txman.Transaction, name string, hooks []txman.Callback) error {
+	for _, hook := range hooks {
+		if err := tx.Do(ctx, hook, nil, txman.WithName(name+" hook")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hookCallbacks prepares callbacks for hooks. They must be created before the
+// transaction begins, so that hosts share state of hooks that run once.
+func (app *App) hookCallbacks(hooks []config.Hook, hc hookContext) []txman.Callback {
+	callbacks := make([]txman.Callback, 0, len(hooks))
+	for _, hook := range hooks {
+		callbacks = append(callbacks, app.hookCallback(hook, hc))
+	}
+	return callbacks
+}
+
+// runHooks runs hooks outside of a transaction.
+func (app *App) runHooks(ctx context.Context, hooks []config.Hook, hc hookContext) error {
+	for _, hook := range hooks {
+		if !hook.Remote {
+			if err := app.runLocalHook(ctx, hook, hc); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := app.txmanager.Execute(ctx, app.hookCallback(hook, hc)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/localexec"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/notify"
+	"github.com/lex-unix/faino/internal/txman"
+)
+
+// gitInfo describes the local commit being deployed and who deploys it.
+type gitInfo struct {
+	commit        string
+	commitMessage string
+	operator      string
+}
+
+// loadGitInfo reads commit and operator from local git repository once per App.
+// Missing git or repository is not an error, fields are left empty.
+func (app *App) loadGitInfo(ctx context.Context) gitInfo {
+	app.gitInfoOnce.Do(func() {
+		output := func(cmd string) string {
+			var out bytes.Buffer
+			if err := app.lexec.Run(ctx, cmd, localexec.WithStdout(&out)); err != nil {
+				return ""
+			}
+			return strings.TrimSpace(out.String())
+		}
+
+		app.gitInfo.commit = output(command.CommitHash())
+		app.gitInfo.commitMessage = output(command.CommitMessage())
+		app.gitInfo.operator = output(command.GitUserName())
+		if app.gitInfo.operator == "" {
+			app.gitInfo.operator = os.Getenv("USER")
+		}
+	})
+	return app.gitInfo
+}
+
+// notify sends event about version to webhooks.
+func (app *App) notify(ctx context.Context, event notify.Event, version string, duration time.Duration, err error) {
+	if app.notifier == nil {
+		return
+	}
+
+	git := app.loadGitInfo(ctx)
+	msg := notify.Message{
+		Event:         event,
+		Service:       config.Get().Service,
+		Version:       version,
+		Commit:        git.commit,
+		CommitMessage: git.commitMessage,
+		Operator:      git.operator,
+	}
+	if duration > 0 {
+		msg.Duration = duration.Round(time.Second).String()
+	}
+	if err != nil {
+		msg.Error = sshexec.RedactCommand(err)
+	}
+
+	if event != notify.EventDeployStart {
+		status := notify.HostStatusDeployed
+		if event == notify.EventRollback {
+			status = notify.HostStatusRolledBack
+		}
+		msg.Hosts = transactionOutcomes(err, status)
+	}
+
+	// deliver notifications even if the deploy was interrupted
+	app.notifier.Notify(context.WithoutCancel(ctx), msg)
+}
+
+// transactionOutcomes derives per host outcome of a transaction from its error.
+// On success every host has status, otherwise the host that failed the
+// transaction is reported as failed and others as rolled back. If the transaction
+// met its quorum, only hosts it failed on are reported as failed.
+func transactionOutcomes(err error, status string) []notify.HostOutcome {
+	var hostErr *txman.HostError
+	var partialErr *txman.PartialError
+	outcomes := make([]notify.HostOutcome, 0)
+	switch {
+	case err == nil:
+		for _, host := range targetHosts() {
+			outcomes = append(outcomes, notify.HostOutcome{Host: host, Status: status})
+		}
+	case errors.As(err, &partialErr):
+		failed := make(map[string]error, len(partialErr.Failed))
+		for _, f := range partialErr.Failed {
+			failed[f.Host] = f.Err
+		}
+		for _, host := range targetHosts() {
+			outcome := notify.HostOutcome{Host: host, Status: status}
+			if err, ok := failed[host]; ok {
+				outcome.Status = notify.HostStatusFailed
+				outcome.Error = sshexec.RedactCommand(err)
+			}
+			outcomes = append(outcomes, outcome)
+		}
+	case errors.As(err, &hostErr):
+		for _, host := range targetHosts() {
+			outcome := notify.HostOutcome{Host: host, Status: notify.HostStatusRolledBack}
+			if host == hostErr.Host {
+				outcome.Status = notify.HostStatusFailed
+				outcome.Error = sshexec.RedactCommand(hostErr.Err)
+			}
+			outcomes = append(outcomes, outcome)
+		}
+	}
+	return outcomes
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/localexec"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/notify"
+	"github.com/lex-unix/faino/internal/txman"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noGit fails every local command, as if git was not installed.
+type noGit struct{}
+
+func (noGit) Run(ctx context.Context, cmd string, options ...localexec.Option) error {
+	return errors.New("git: command not found")
+}
+
+// notifierStub records delivered messages.
+type notifierStub struct {
+	messages []notify.Message
+}
+
+func (n *notifierStub) Notify(ctx context.Context, msg notify.Message) {
+	n.messages = append(n.messages, msg)
+}
+
+func TestNotifyRedactsCommands(t *testing.T) {
+	useConfig(t, &config.Config{Service: "app", Servers: []string{"host1", "host2"}})
+	notifier := &notifierStub{}
+	a := New(noGit{}, nil, notifier)
+	cmdErr := &sshexec.CommandError{Host: "host1", Command: "docker run --env DB_PASSWORD=hunter2 app:v2", Code: 125}
+
+	a.notify(context.Background(), notify.EventDeployFailure, "v2", 0, &txman.HostError{Host: "host1", Err: cmdErr})
+	a.notify(context.Background(), notify.EventDeploySuccess, "v2", 0, &txman.PartialError{Hosts: 2, Failed: []*txman.HostError{{Host: "host1", Err: cmdErr}}})
+
+	require.Len(t, notifier.messages, 2)
+	for _, msg := range notifier.messages {
+		payload, err := json.Marshal(msg)
+		require.NoError(t, err)
+		assert.NotContains(t, string(payload), "hunter2")
+		assert.Contains(t, msg.Error, "<redacted> on host1")
+	}
+}
@@ -0,0 +1,258 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/localexec"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+)
+
+const (
+	dockerDataDir        = "/var/lib/docker"
+	dockerConfigFilePath = "~/.docker/config.json"
+	dockerHubAuthServer  = "https://index.docker.io/v1/"
+	dockerHubRegistry    = "docker.io"
+)
+
+// CheckResult is the outcome of a single preflight check. Err is nil if the check passed.
+// Failed warnings are reported, but do not fail preflight.
+type CheckResult struct {
+	Name    string
+	Err     error
+	Warning bool
+}
+
+// PreflightReport holds results of checks run on the local machine and on every host.
+type PreflightReport struct {
+	Local []CheckResult
+	Hosts map[string][]CheckResult
+}
+
+// Failed reports whether any check failed.
+func (r PreflightReport) Failed() bool {
+	for _, c := range r.Local {
+		if c.Err != nil && !c.Warning {
+			return true
+		}
+	}
+	for _, checks := range r.Hosts {
+		for _, c := range checks {
+			if c.Err != nil && !c.Warning {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PreflightError is returned by Deploy when preflight checks failed.
+type PreflightError struct {
+	Report PreflightReport
+}
+
+func (e *PreflightError) Error() string {
+	return "preflight checks failed"
+}
+
+type localCheck struct {
+	name string
+	run  func(ctx context.Context, lexec localexec.Service) error
+}
+
+type remoteCheck struct {
+	name    string
+	warning bool
+	run     func(ctx context.Context, client sshexec.Service) error
+}
+
+func (app *App) localChecks() []localCheck {
+	cfg := config.Get()
+	checks := []localCheck{
+		{
+			name: "docker is running",
+			run: func(ctx context.Context, lexec localexec.Service) error {
+				return lexec.Run(ctx, command.IsDockerRunning())
+			},
+		},
+	}
+	if cfg.Build.Driver != "docker" {
+		checks = append(checks, localCheck{
+			name: "docker buildx is installed",
+			run: func(ctx context.Context, lexec localexec.Service) error {
+				return lexec.Run(ctx, command.IsBuildxInstalled())
+			},
+		})
+	}
+	return checks
+}
+
+func (app *App) remoteChecks() []remoteCheck {
+	cfg := config.Get()
+	return []remoteCheck{
+		{
+			name: fmt.Sprintf("%s is running", serverRuntime()),
+			run: func(ctx context.Context, client sshexec.Service) error {
+				return client.Run(ctx, serverRuntime().IsRuntimeRunning(), sshexec.WithRetry())
+			},
+		},
+		{
+			name: fmt.Sprintf("network %s exists", command.Network),
+			run: func(ctx context.Context, client sshexec.Service) error {
+				_, err := app.containerEngine(client).NetworkInspect(ctx, command.Network)
+				if err != nil {
+					return fmt.Errorf("network is missing, run `faino setup`: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			name: "history file exists",
+			run: func(ctx context.Context, client sshexec.Service) error {
+				_, err := client.Stat(ctx, app.historyFilePath)
+				if errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("%s is missing, run `faino setup`", app.historyFilePath)
+				}
+				return err
+			},
+		},
+		{
+			// registries may allow anonymous pulls, so missing credentials are not fatal
+			name:    "logged in to registry",
+			warning: true,
+			run: func(ctx context.Context, client sshexec.Service) error {
+				return checkRegistryLogin(ctx, client, cfg.Registry.Server)
+			},
+		},
+		{
+			name: "enough disk space",
+			run: func(ctx context.Context, client sshexec.Service) error {
+				return checkDiskSpace(ctx, client, cfg.Preflight.MinDiskSpace)
+			},
+		},
+	}
+}
+
+// Preflight runs checks on the local machine and on every host. All checks are
+// run even if some of them fail, so that the report is complete.
+func (app *App) Preflight(ctx context.Context) (PreflightReport, error) {
+	report := PreflightReport{Hosts: make(map[string][]CheckResult)}
+
+	for _, check := range app.localChecks() {
+		err := check.run(ctx, app.lexec)
+		report.Local = append(report.Local, CheckResult{Name: check.name, Err: err})
+	}
+
+	var mu sync.Mutex
+	checks := app.remoteChecks()
+	err := app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
+		results := make([]CheckResult, 0, len(checks))
+		var failed error
+		for _, check := range checks {
+			err := check.run(ctx, client)
+			results = append(results, CheckResult{Name: check.name, Err: err, Warning: check.warning})
+			if err != nil && !check.warning && failed == nil {
+				failed = fmt.Errorf("host %s: %s: %w", client.Host(), check.name, err)
+			}
+		}
+
+		mu.Lock()
+		report.Hosts[client.Host()] = results
+		mu.Unlock()
+
+		return failed
+	})
+
+	// failed checks are in the report, only return errors that prevented running them
+	if err != nil && !report.Failed() {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// checkRegistryLogin verifies that docker on the host has credentials for server.
+// Credentials kept in a credential store cannot be inspected, so they are trusted.
+func checkRegistryLogin(ctx context.Context, client sshexec.Service, server string) error {
+	data, err := client.ReadFile(ctx, dockerConfigFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("not logged in to %s, run `faino registry login`", server)
+	}
+	if err != nil {
+		return err
+	}
+
+	var dockerConfig struct {
+		Auths       map[string]json.RawMessage `json:"auths"`
+		CredsStore  string                     `json:"credsStore"`
+		CredHelpers map[string]string          `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", dockerConfigFilePath, err)
+	}
+
+	if dockerConfig.CredsStore != "" {
+		return nil
+	}
+
+	keys := []string{server, "https://" + server}
+	if server == dockerHubRegistry {
+		keys = append(keys, dockerHubAuthServer, "index.docker.io")
+	}
+	for _, key := range keys {
+		if _, ok := dockerConfig.Auths[key]; ok {
+			return nil
+		}
+		if _, ok := dockerConfig.CredHelpers[key]; ok {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("not logged in to %s, run `faino registry login`", server)
+}
+
+// checkDiskSpace verifies that filesystem holding docker data has at least minMB megabytes free.
+func checkDiskSpace(ctx context.Context, client sshexec.Service, minMB int64) error {
+	if minMB <= 0 {
+		return nil
+	}
+
+	var out bytes.Buffer
+	err := client.Run(ctx, command.DiskFree(dockerDataDir), sshexec.WithStdout(&out), sshexec.WithRetry())
+	if err != nil {
+		return err
+	}
+
+	freeKB, err := parseDiskFree(out.String())
+	if err != nil {
+		return err
+	}
+	if freeMB := freeKB / 1024; freeMB < minMB {
+		return fmt.Errorf("only %d MB free, need at least %d MB", freeMB, minMB)
+	}
+
+	return nil
+}
+
+// parseDiskFree extracts available kilobytes from `df -Pk` output.
+func parseDiskFree(out string) (int64, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return 0, fmt.Errorf("unexpected df output: %q", out)
+	}
+	free, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected df output: %q", out)
+	}
+	return free, nil
+}
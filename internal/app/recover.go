@@ -0,0 +1,21 @@
+package app
+
+import (
+	"context"
+
+	"github.com/lex-unix/faino/internal/txman"
+)
+
+// PendingTransactions returns journals of transactions interrupted before they
+// were committed or rolled back.
+func (app *App) PendingTransactions(ctx context.Context) ([]txman.Journal, error) {
+	return app.txmanager.Pending(ctx)
+}
+
+// Recover rolls back interrupted transaction id, or commits it if forward is set.
+func (app *App) Recover(ctx context.Context, id string, forward bool) error {
+	op := app.beginAudit(AuditRecover, "", id)
+	err := app.txmanager.Recover(ctx, id, forward)
+	app.finishAudit(ctx, op, err)
+	return err
+}
@@ -0,0 +1,179 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/engine"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/txman"
+)
+
+const (
+	releaseEnvDir      = "~/.faino/env"
+	releaseEnvFileMode = 0600
+)
+
+// releaseEnvFile keeps env the container of version was started with, so that
+// the container can be recreated on rollback even if config has changed since.
+func releaseEnvFile(service, version string) string {
+	return path.Join(releaseEnvDir, fmt.Sprintf("%s-%s.env", service, version))
+}
+
+// releaseEnvTx records env of version on hosts as a transaction step.
+func releaseEnvTx(ctx context.Context, tx txman.Transaction, version string, env map[string]string) error {
+	file := releaseEnvFile(config.Get().Service, version)
+	return tx.Do(ctx, func(ctx context.Context, client sshexec.Service) error {
+		if err := client.MkdirAll(ctx, releaseEnvDir); err != nil {
+			return err
+		}
+		return client.WriteFile(ctx, file, envFileContents(env), releaseEnvFileMode)
+	}, func(ctx context.Context, client sshexec.Service) error {
+		err := client.Remove(ctx, file)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}, txman.WithName("write release env"), txman.WithRollbackCommand(command.RemoveFile(file)))
+}
+
+// readReleaseEnv reads env recorded for version on the host. Versions deployed
+// before env was recorded fall back to env from config.
+func readReleaseEnv(ctx context.Context, client sshexec.Service, version string) (map[string]string, error) {
+	cfg := config.Get()
+	data, err := client.ReadFile(ctx, releaseEnvFile(cfg.Service, version))
+	if errors.Is(err, os.ErrNotExist) {
+		logging.WarnHostf(client.Host(), "env of version %s was not recorded, using env from config", version)
+		return cfg.Env, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseEnvFile(data), nil
+}
+
+// parseEnvFile parses env in docker --env-file format.
+func parseEnvFile(data []byte) map[string]string {
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		k, v, _ := strings.Cut(line, "=")
+		env[k] = v
+	}
+	return env
+}
+
+// targetState tells how the container of a rollback target can be started on a host.
+type targetState struct {
+	containerExists bool
+	imageExists     bool
+}
+
+// inspectRollbackTarget checks container and image of entry on every host.
+// It fails if a host has neither, since the version cannot be started there.
+func (app *App) inspectRollbackTarget(ctx context.Context, entry HistoryEntry) (map[string]targetState, error) {
+	container := fmt.Sprintf("%s-%s", config.Get().Service, entry.Version)
+	image := entryImage(entry)
+
+	var mu sync.Mutex
+	states := make(map[string]targetState)
+	err := app.txmanager.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
+		var state targetState
+		eng := app.containerEngine(client)
+
+		_, err := eng.ContainerInspect(ctx, container)
+		if err != nil && !errors.Is(err, engine.ErrNotFound) {
+			return err
+		}
+		state.containerExists = err == nil
+
+		if !state.containerExists {
+			_, err := eng.ImageInspect(ctx, image)
+			if err != nil && !errors.Is(err, engine.ErrNotFound) {
+				return err
+			}
+			state.imageExists = err == nil
+		}
+
+		mu.Lock()
+		states[client.Host()] = state
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]string, 0)
+	for host, state := range states {
+		if !state.containerExists && !state.imageExists {
+			missing = append(missing, host)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("neither container %s nor image %s exists on %s", container, image, strings.Join(missing, ", "))
+	}
+
+	return states, nil
+}
+
+// startTargetTx starts container of entry. If the container was removed on a host,
+// it is recreated from the recorded image, env and volumes.
+func startTargetTx(ctx context.Context, tx txman.Transaction, entry HistoryEntry, states map[string]targetState) error {
+	cfg := config.Get()
+	runtime := serverRuntime()
+	container := fmt.Sprintf("%s-%s", cfg.Service, entry.Version)
+	volumes := entry.Volumes
+	if volumes == nil {
+		volumes = cfg.Volumes
+	}
+
+	// recreated container is removed on rollback, an existing one is only stopped
+	rollbackCmd := runtime.StopContainer(container)
+	if !states[tx.Host()].containerExists {
+		rollbackCmd += " && " + runtime.RemoveContainer(container)
+	}
+
+	return tx.Do(ctx, func(ctx context.Context, client sshexec.Service) error {
+		if states[client.Host()].containerExists {
+			return client.Run(ctx, runtime.StartContainer(container))
+		}
+
+		env, err := readReleaseEnv(ctx, client, entry.Version)
+		if err != nil {
+			return err
+		}
+		logging.InfoHostf(client.Host(), "container %s is missing, recreating it from %s", container, entryImage(entry))
+		return client.Run(ctx, runtime.RunContainer(entryImage(entry), container, cfg.Service, env, volumes))
+	}, func(ctx context.Context, client sshexec.Service) error {
+		if states[client.Host()].containerExists {
+			return client.Run(ctx, runtime.StopContainer(container))
+		}
+		if err := client.Run(ctx, runtime.StopContainer(container)); err != nil {
+			return err
+		}
+		return client.Run(ctx, runtime.RemoveContainer(container))
+	}, txman.WithName("start target container"), txman.WithRollbackCommand(rollbackCmd))
+}
+
+// entryImage returns image recorded in history, entries of the legacy format have none.
+func entryImage(entry HistoryEntry) string {
+	if entry.Image != "" {
+		return entry.Image
+	}
+	return imageName(entry.Version)
+}
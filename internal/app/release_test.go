@@ -0,0 +1,18 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	env := map[string]string{
+		"DATABASE_URL": "postgres://user:p=ss@db/app",
+		"EMPTY":        "",
+	}
+
+	got := parseEnvFile(append([]byte("# comment\n\n"), envFileContents(env)...))
+
+	assert.Equal(t, env, got)
+}
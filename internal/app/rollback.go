@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// RollbackPlan describes what rollback will change. Current is empty if no version is active.
+type RollbackPlan struct {
+	Current HistoryEntry
+	Target  HistoryEntry
+}
+
+// PlanRollback picks the version to roll back to and verifies that it can be
+// started on every host. If version is empty, target is the version that was active
+// steps activations ago, i.e. steps=1 is the previously active version.
+func (app *App) PlanRollback(ctx context.Context, version string, steps int) (RollbackPlan, error) {
+	if err := app.LoadHistory(ctx); err != nil {
+		return RollbackPlan{}, fmt.Errorf("failed to read history at %s: %w", app.historyFilePath, err)
+	}
+
+	var plan RollbackPlan
+	if i := slices.IndexFunc(app.history, func(h HistoryEntry) bool { return h.Status == HistoryStatusActive }); i >= 0 {
+		plan.Current = app.history[i]
+	}
+
+	if version != "" {
+		i := slices.IndexFunc(app.history, func(h HistoryEntry) bool { return h.Version == version })
+		if i < 0 {
+			return RollbackPlan{}, fmt.Errorf("version %s does not exist", version)
+		}
+		plan.Target = app.history[i]
+	} else {
+		target, err := previousVersion(app.history, steps)
+		if err != nil {
+			return RollbackPlan{}, err
+		}
+		plan.Target = target
+	}
+
+	switch plan.Target.Status {
+	case HistoryStatusActive:
+		return RollbackPlan{}, fmt.Errorf("version %s is already active", plan.Target.Version)
+	case HistoryStatusFailed:
+		return RollbackPlan{}, fmt.Errorf("version %s failed to deploy", plan.Target.Version)
+	}
+
+	if _, err := app.inspectRollbackTarget(ctx, plan.Target); err != nil {
+		return RollbackPlan{}, err
+	}
+
+	return plan, nil
+}
+
+// previousVersion returns the version that was active steps activations before the
+// current one. Failed versions were never active and are skipped.
+func previousVersion(history []HistoryEntry, steps int) (HistoryEntry, error) {
+	if steps < 1 {
+		return HistoryEntry{}, fmt.Errorf("steps must be at least 1, got %d", steps)
+	}
+
+	candidates := make([]HistoryEntry, 0, len(history))
+	for _, h := range history {
+		if h.Status != HistoryStatusActive && h.Status != HistoryStatusFailed && !h.ActivatedAt.IsZero() {
+			candidates = append(candidates, h)
+		}
+	}
+	slices.SortFunc(candidates, func(a, b HistoryEntry) int { return b.ActivatedAt.Compare(a.ActivatedAt) })
+
+	if len(candidates) == 0 {
+		return HistoryEntry{}, errors.New("there is no previous version to roll back to")
+	}
+	if steps > len(candidates) {
+		return HistoryEntry{}, fmt.Errorf("cannot roll back %d steps, there are only %d previous versions", steps, len(candidates))
+	}
+	return candidates[steps-1], nil
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/logging"
+)
+
+const (
+	InstallMethodPackage = "package"
+	InstallMethodScript  = "script"
+)
+
+type SetupOptions struct {
+	// InstallDocker installs the container runtime on hosts where it is missing.
+	InstallDocker bool
+	// InstallMethod is either InstallMethodPackage or InstallMethodScript.
+	InstallMethod string
+	// User is the SSH user added to the docker group after installation.
+	User string
+}
+
+// HostReadiness describes whether a host is ready to receive deployments. Docker
+// fields describe the configured container runtime, which may be podman.
+type HostReadiness struct {
+	DockerInstalled bool
+	DockerRunning   bool
+	// DockerInstalledNow is true if Docker was installed during this setup.
+	DockerInstalledNow bool
+	Err                error
+}
+
+// SetupReport maps host to its readiness after setup.
+type SetupReport map[string]HostReadiness
+
+type setupReporter struct {
+	mu     sync.Mutex
+	report SetupReport
+}
+
+func (r *setupReporter) set(host string, readiness HostReadiness) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report[host] = readiness
+}
+
+// ensureRuntime checks that the container runtime is installed and running on
+// the host, installing and starting it if opts allow.
+func ensureRuntime(ctx context.Context, client sshexec.Service, opts SetupOptions) (HostReadiness, error) {
+	var r HostReadiness
+	host := client.Host()
+	runtime := serverRuntime()
+
+	err := client.Run(ctx, runtime.IsRuntimeInstalled(), sshexec.WithRetry())
+	var cmdErr *sshexec.CommandError
+	switch {
+	case err == nil:
+		r.DockerInstalled = true
+	case errors.As(err, &cmdErr) && cmdErr.NotFound():
+		if !opts.InstallDocker {
+			return r, fmt.Errorf("%s is not installed on %s, run setup with --install-docker to install it", runtime, host)
+		}
+		if err := installRuntime(ctx, client, opts); err != nil {
+			return r, err
+		}
+		r.DockerInstalled = true
+		r.DockerInstalledNow = true
+	default:
+		return r, err
+	}
+
+	err = client.Run(ctx, runtime.IsRuntimeRunning(), sshexec.WithRetry())
+	if err != nil && opts.InstallDocker && !r.DockerInstalledNow {
+		logging.InfoHostf(host, "starting %s", runtime)
+		if err := client.Run(ctx, enableRuntime()); err != nil {
+			return r, fmt.Errorf("failed to start %s on %s: %w", runtime, host, err)
+		}
+		err = client.Run(ctx, runtime.IsRuntimeRunning(), sshexec.WithRetry())
+	}
+	if err != nil {
+		if runtime == command.RuntimeDocker && r.DockerInstalledNow && opts.User != "root" {
+			return r, fmt.Errorf("docker was installed on %s, run setup again so that %s joins the docker group", host, opts.User)
+		}
+		return r, fmt.Errorf("%s is not running on %s: %w", runtime, host, err)
+	}
+	r.DockerRunning = true
+
+	return r, nil
+}
+
+func installRuntime(ctx context.Context, client sshexec.Service, opts SetupOptions) error {
+	host := client.Host()
+	runtime := serverRuntime()
+
+	var install string
+	switch {
+	case runtime == command.RuntimePodman && opts.InstallMethod == InstallMethodScript:
+		return fmt.Errorf("podman can only be installed with %s method", InstallMethodPackage)
+	case runtime == command.RuntimePodman:
+		install = command.InstallPodmanPackage()
+	case opts.InstallMethod == InstallMethodScript:
+		install = command.InstallDockerScript()
+	default:
+		install = command.InstallDockerPackage()
+	}
+
+	logging.InfoHostf(host, "installing %s using %s method", runtime, opts.InstallMethod)
+	if err := client.Run(ctx, install); err != nil {
+		return fmt.Errorf("failed to install %s on %s: %w", runtime, host, err)
+	}
+	if err := client.Run(ctx, enableRuntime()); err != nil {
+		return fmt.Errorf("failed to start %s on %s: %w", runtime, host, err)
+	}
+	// podman runs containers of the user without a group
+	if runtime == command.RuntimeDocker && opts.User != "" && opts.User != "root" {
+		logging.InfoHostf(host, "adding %s to docker group", opts.User)
+		if err := client.Run(ctx, command.AddUserToDockerGroup(opts.User)); err != nil {
+			return fmt.Errorf("failed to add %s to docker group on %s: %w", opts.User, host, err)
+		}
+	}
+
+	return nil
+}
+
+func enableRuntime() string {
+	if serverRuntime() == command.RuntimePodman {
+		return command.EnablePodman()
+	}
+	return command.EnableDocker()
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/config"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/txman"
+	"github.com/stretchr/testify/assert"
+)
+
+// hostStub is a host whose commands are handled by run.
+type hostStub struct {
+	sshexec.Service
+	host string
+	run  func(cmd string) error
+}
+
+func (h *hostStub) Host() string { return h.host }
+
+func (h *hostStub) Run(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+	return h.run(cmd)
+}
+
+func (h *hostStub) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	return nil, os.ErrNotExist
+}
+
+func TestSetupReportsEveryHost(t *testing.T) {
+	useConfig(t, &config.Config{})
+	missing := &hostStub{host: "missing", run: func(cmd string) error {
+		return &sshexec.CommandError{Host: "missing", Command: cmd, Code: 127}
+	}}
+	stopped := &hostStub{host: "stopped", run: func(cmd string) error {
+		time.Sleep(50 * time.Millisecond)
+		if cmd == command.RuntimeDocker.IsRuntimeInstalled() {
+			return nil
+		}
+		return errors.New("cannot connect to the docker daemon")
+	}}
+	a := New(nil, txman.New(missing, stopped), nil)
+
+	report, err := a.Setup(context.Background(), SetupOptions{})
+
+	assert.Error(t, err)
+	if assert.Len(t, report, 2) {
+		assert.False(t, report["missing"].DockerInstalled)
+		assert.True(t, report["stopped"].DockerInstalled)
+		assert.False(t, report["stopped"].DockerRunning)
+		assert.Error(t, report["stopped"].Err)
+	}
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/lex-unix/faino/internal/exec/sshexec"
 	"github.com/lex-unix/faino/internal/txman"
@@ -16,7 +17,7 @@ type RemoteFileContent struct {
 
 func ReadRemoteFile(path string, resultsCh chan<- RemoteFileContent) txman.Callback {
 	return func(ctx context.Context, client sshexec.Service) error {
-		data, err := client.ReadFile(path)
+		data, err := client.ReadFile(ctx, path)
 		result := RemoteFileContent{
 			host: client.Host(),
 			data: data,
@@ -30,9 +31,17 @@ func ReadRemoteFile(path string, resultsCh chan<- RemoteFileContent) txman.Callb
 	}
 }
 
-func WriteToRemoteFile(path string, data []byte) txman.Callback {
+func WriteToRemoteFile(path string, data []byte, perm os.FileMode) txman.Callback {
 	return func(ctx context.Context, client sshexec.Service) error {
-		return client.WriteFile(path, data)
+		return client.WriteFile(ctx, path, data, perm)
+	}
+}
+
+// pullImage pulls img on the host. Pulling is idempotent, so it is retried
+// if the connection drops.
+func pullImage(img string) txman.Callback {
+	return func(ctx context.Context, client sshexec.Service) error {
+		return client.Run(ctx, serverRuntime().PullImage(img), sshexec.WithRetry())
 	}
 }
 
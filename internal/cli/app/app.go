@@ -5,6 +5,7 @@ import (
 
 	execCmd "github.com/lex-unix/faino/internal/cli/app/exec"
 	restartCmd "github.com/lex-unix/faino/internal/cli/app/restart"
+	runCmd "github.com/lex-unix/faino/internal/cli/app/run"
 	showCmd "github.com/lex-unix/faino/internal/cli/app/show"
 	startCmd "github.com/lex-unix/faino/internal/cli/app/start"
 	stopCmd "github.com/lex-unix/faino/internal/cli/app/stop"
@@ -23,6 +24,7 @@ func NewCmdApp(ctx context.Context, f *cliutil.Factory) *cobra.Command {
 	cmd.AddCommand(startCmd.NewCmdStart(ctx, f))
 	cmd.AddCommand(restartCmd.NewCmdRestart(ctx, f))
 	cmd.AddCommand(execCmd.NewCmdExec(ctx, f))
+	cmd.AddCommand(runCmd.NewCmdRun(ctx, f))
 
 	return cmd
 }
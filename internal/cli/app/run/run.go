@@ -0,0 +1,74 @@
+package run
+
+import (
+	"context"
+	"os"
+
+	"al.essio.dev/pkg/shellescape"
+	"github.com/lex-unix/faino/internal/cli/cliutil"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+type RunOptions struct {
+	interactive bool
+	host        string
+	version     string
+}
+
+func NewCmdRun(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	opts := RunOptions{
+		interactive: false,
+	}
+	cmd := &cobra.Command{
+		Use:   "run [flags] -- CMD",
+		Short: "Run a command in a new one-off container of the app on a single server",
+		Long: "Run a command in a new container of the app that is removed when the command exits.\n" +
+			"The container gets the same env and volumes as the app, but does not affect the running one.\n" +
+			"Command runs on the first server unless --host is given.",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := f.Config()
+			if err != nil {
+				return err
+			}
+			// one-off containers are run on a single server
+			if cfg.Host == "" {
+				cfg.Host = cfg.Servers[0]
+			}
+
+			logging.Default().SetLevel(logging.LevelError)
+
+			app, err := f.App()
+			if err != nil {
+				return err
+			}
+
+			// a single argument is a shell command line, e.g. "bin/migrate && bin/seed",
+			// several arguments are quoted so that `-- sh -c "echo hi"` keeps them apart
+			remoteCommand := args[0]
+			if len(args) > 1 {
+				remoteCommand = shellescape.QuoteCommand(args)
+			}
+
+			if opts.interactive {
+				return app.RunServiceInteractive(ctx, opts.version, remoteCommand)
+			}
+
+			output, err := app.RunService(ctx, opts.version, remoteCommand)
+			if err != nil {
+				return err
+			}
+
+			cliutil.PrintOutput(output, os.Stdout)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&opts.interactive, "interactive", "i", false, "Start interactive session in the container")
+	cmd.Flags().StringVarP(&opts.host, "host", "H", "", "Run command on specified server")
+	cmd.Flags().StringVar(&opts.version, "version", "", "Version of the app to run (default: current version)")
+
+	return cmd
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lex-unix/faino/internal/app"
+	"github.com/lex-unix/faino/internal/cli/cliutil"
+	"github.com/spf13/cobra"
+)
+
+type AuditOptions struct {
+	operation string
+	since     time.Duration
+	failed    bool
+	limit     int
+	local     bool
+	json      bool
+}
+
+func NewCmdAudit(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	opts := AuditOptions{}
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show audit log of operations performed on servers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, err := f.App()
+			if err != nil {
+				return err
+			}
+
+			filter := app.AuditFilter{
+				Operation: opts.operation,
+				Failed:    opts.failed,
+				Limit:     opts.limit,
+				Local:     opts.local,
+			}
+			if opts.since > 0 {
+				filter.Since = time.Now().Add(-opts.since)
+			}
+
+			entries, err := a.Audit(ctx, filter)
+			if err != nil {
+				return err
+			}
+
+			if opts.json {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(entries)
+			}
+			printEntries(entries, os.Stdout)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.operation, "operation", "o", "", "Show only operation, e.g. \"deploy\" or \"proxy reboot\"")
+	cmd.Flags().DurationVar(&opts.since, "since", 0, "Show entries newer than duration, e.g. 24h")
+	cmd.Flags().BoolVar(&opts.failed, "failed", false, "Show only failed operations")
+	cmd.Flags().IntVarP(&opts.limit, "limit", "n", 20, "Maximum number of entries to show, 0 shows all")
+	cmd.Flags().BoolVar(&opts.local, "local", false, "Read local audit log instead of logs on servers")
+	cmd.Flags().BoolVar(&opts.json, "json", false, "Print entries as JSON")
+
+	return cmd
+}
+
+func printEntries(entries []app.AuditEntry, out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tOPERATION\tVERSION\tOPERATOR\tCOMMIT\tDURATION\tOUTCOME")
+	for _, e := range entries {
+		operation := e.Operation
+		if e.Command != "" {
+			operation = fmt.Sprintf("%s %q", operation, e.Command)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			operation,
+			cliutil.Dash(e.Version),
+			cliutil.Dash(e.Operator),
+			cliutil.Dash(e.Commit),
+			e.Duration.Round(time.Millisecond),
+			outcome(e),
+		)
+	}
+	w.Flush()
+}
+
+func outcome(e app.AuditEntry) string {
+	if e.Outcome != app.AuditOutcomeFailure {
+		return e.Outcome
+	}
+	failed := make([]string, 0)
+	for _, h := range e.Hosts {
+		if h.Error != "" {
+			failed = append(failed, fmt.Sprintf("%s: %s", h.Host, h.Error))
+		}
+	}
+	if len(failed) == 0 {
+		return fmt.Sprintf("%s: %s", e.Outcome, e.Error)
+	}
+	return fmt.Sprintf("%s: %s", e.Outcome, strings.Join(failed, "; "))
+}
@@ -0,0 +1,27 @@
+package cliutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lex-unix/faino/internal/app"
+	"github.com/lex-unix/faino/internal/txman"
+)
+
+// ObserveEvents writes events of transactions run by a to file at path as JSON
+// lines. The returned closer must be closed once the command finished. The file
+// is readable only by the user, since errors may include command output.
+func ObserveEvents(a *app.App, path string) (io.Closer, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create events file: %w", err)
+	}
+	// an existing file keeps its mode on open
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create events file: %w", err)
+	}
+	a.ObserveTransactions(txman.NewJSONObserver(f))
+	return f, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/lex-unix/faino/internal/config"
 	"github.com/lex-unix/faino/internal/exec/localexec"
 	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/notify"
 	"github.com/lex-unix/faino/internal/txman"
 )
 
@@ -57,16 +58,37 @@ func txManFunc(f *Factory) func() (txman.Service, error) {
 			hosts = append(hosts, cfg.Servers...)
 		}
 
+		retry := sshexec.RetryPolicy{
+			Attempts:   cfg.SSH.Retry.Attempts,
+			Backoff:    cfg.SSH.Retry.Backoff,
+			MaxBackoff: cfg.SSH.Retry.MaxBackoff,
+		}
+
+		options := []sshexec.ClientOption{sshexec.WithRetryPolicy(retry)}
+		if cfg.SSH.TrustOnFirstUse || cfg.AcceptHostKeys {
+			options = append(options, sshexec.WithHostKeyConfirm(hostKeyConfirmFunc(cfg.AcceptHostKeys)))
+		}
+
 		var clients []sshexec.Service
 		for _, host := range hosts {
-			sshClient, err := sshexec.New(host, cfg.SSH.User, cfg.SSH.Port)
+			if host == sshexec.LocalHost {
+				local, err := sshexec.NewLocal()
+				if err != nil {
+					return nil, err
+				}
+				clients = append(clients, local)
+				continue
+			}
+			sshClient, err := sshexec.New(host, cfg.SSH.User, cfg.SSH.Port, options...)
 			if err != nil {
 				return nil, fmt.Errorf("failed to connect to host %s: %s", host, err)
 			}
 			clients = append(clients, sshClient)
 		}
 
-		return txman.New(clients...), nil
+		m := txman.New(clients...)
+		m.EnableJournal(txman.DefaultJournalDir)
+		return m, nil
 	}
 }
 
@@ -76,7 +98,28 @@ func appFunc(f *Factory) func() (*app.App, error) {
 		if err != nil {
 			return nil, err
 		}
+		cfg, err := f.Config()
+		if err != nil {
+			return nil, err
+		}
 		le := localexec.New()
-		return app.New(le, txman), nil
+		return app.New(le, txman, notifierFromConfig(cfg)), nil
+	}
+}
+
+func notifierFromConfig(cfg *config.Config) notify.Notifier {
+	if len(cfg.Notifications.Webhooks) == 0 {
+		return notify.Noop{}
 	}
+
+	hooks := make([]notify.Webhook, 0, len(cfg.Notifications.Webhooks))
+	for _, h := range cfg.Notifications.Webhooks {
+		events := make([]notify.Event, 0, len(h.Events))
+		for _, e := range h.Events {
+			events = append(events, notify.Event(e))
+		}
+		hooks = append(hooks, notify.Webhook{URL: h.URL, Events: events})
+	}
+
+	return notify.New(hooks, cfg.Notifications.Timeout)
 }
@@ -3,8 +3,11 @@ package cliutil
 import (
 	"fmt"
 	"io"
+	"slices"
+	"text/tabwriter"
 
 	"github.com/lex-unix/faino/internal/app"
+	"github.com/lex-unix/faino/internal/txman"
 )
 
 func PrintOutput(output app.HostOutput, out io.Writer) {
@@ -14,3 +17,71 @@ func PrintOutput(output app.HostOutput, out io.Writer) {
 		fmt.Fprintln(out)
 	}
 }
+
+func PrintPreflightReport(report app.PreflightReport, out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tCHECK\tSTATUS")
+	for _, c := range report.Local {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", "local", c.Name, checkStatus(c))
+	}
+
+	hosts := make([]string, 0, len(report.Hosts))
+	for host := range report.Hosts {
+		hosts = append(hosts, host)
+	}
+	slices.Sort(hosts)
+	for _, host := range hosts {
+		for _, c := range report.Hosts[host] {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", host, c.Name, checkStatus(c))
+		}
+	}
+	w.Flush()
+}
+
+func checkStatus(c app.CheckResult) string {
+	switch {
+	case c.Err == nil:
+		return "ok"
+	case c.Warning:
+		return "WARN: " + c.Err.Error()
+	default:
+		return "FAIL: " + c.Err.Error()
+	}
+}
+
+// PrintRollbackReport lists rollback steps that failed or were skipped, together
+// with commands that undo them, in the order they have to be run.
+func PrintRollbackReport(report txman.RollbackReport, out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tSTEP\tNAME\tSTATUS\tERROR")
+	for _, s := range report.Steps {
+		errMsg := ""
+		if s.Err != nil {
+			errMsg = s.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", s.Host, s.Step, s.Name, s.Status, errMsg)
+	}
+	w.Flush()
+
+	manual := report.Manual()
+	if len(manual) == 0 {
+		return
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Undo the following steps manually:")
+	for _, s := range manual {
+		cmd := s.Command
+		if cmd == "" {
+			cmd = "(no rollback command recorded)"
+		}
+		fmt.Fprintf(out, "  %s step %d (%s): %s\n", s.Host, s.Step, s.Name, cmd)
+	}
+}
+
+// Dash returns s, or a dash if it is empty, for table cells.
+func Dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,172 @@
+package cliutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/txman"
+	"golang.org/x/term"
+)
+
+const progressRefresh = 500 * time.Millisecond
+
+// Progress renders step events of a transaction with a line per host. On a
+// terminal the lines are redrawn in place and info logs are muted until Finish,
+// otherwise every state change is printed as a line.
+type Progress struct {
+	out  io.Writer
+	live bool
+
+	mu      sync.Mutex
+	hosts   []string
+	current map[string]txman.StepEvent
+	started map[string]time.Time
+	steps   []txman.StepEvent
+	drawn   int
+
+	level logging.Level
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func NewProgress(out io.Writer) *Progress {
+	p := &Progress{
+		out:     out,
+		current: make(map[string]txman.StepEvent),
+		started: make(map[string]time.Time),
+	}
+	if f, ok := out.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		p.live = logging.Default().Level() > logging.LevelDebug
+	}
+	return p
+}
+
+// Update records e and renders it.
+func (p *Progress) Update(e txman.StepEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.current[e.Host]; !ok {
+		p.hosts = append(p.hosts, e.Host)
+		slices.Sort(p.hosts)
+	}
+	p.current[e.Host] = e
+	switch e.State {
+	case txman.ProgressRunning:
+		p.started[e.Host] = time.Now()
+	case txman.ProgressDone, txman.ProgressFailed:
+		p.steps = append(p.steps, e)
+	}
+
+	if !p.live {
+		if e.State != txman.ProgressPending {
+			fmt.Fprintln(p.out, p.line(e))
+		}
+		return
+	}
+	if p.stop == nil {
+		p.start()
+	}
+	p.redraw()
+}
+
+// start mutes info logs that would break the live view and starts refreshing
+// durations of running steps.
+func (p *Progress) start() {
+	p.level = logging.Default().Level()
+	logging.Default().SetLevel(logging.LevelWarn)
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(progressRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.redraw()
+				p.mu.Unlock()
+			}
+		}
+	}()
+}
+
+func (p *Progress) redraw() {
+	if p.drawn > 0 {
+		// move cursor to the first line of the view
+		fmt.Fprintf(p.out, "\033[%dA", p.drawn)
+	}
+	for _, host := range p.hosts {
+		fmt.Fprintf(p.out, "\033[2K%s\n", p.line(p.current[host]))
+	}
+	p.drawn = len(p.hosts)
+}
+
+func (p *Progress) line(e txman.StepEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] ", e.Host)
+	switch e.State {
+	case txman.ProgressPending:
+		b.WriteString("pending")
+	case txman.ProgressRunning:
+		fmt.Fprintf(&b, "step %d %s: running %s", e.Step, e.Name, formatDuration(time.Since(p.started[e.Host])))
+	case txman.ProgressDone:
+		fmt.Fprintf(&b, "step %d %s: done in %s", e.Step, e.Name, formatDuration(e.Duration))
+	case txman.ProgressFailed:
+		fmt.Fprintf(&b, "step %d %s: failed after %s: %s", e.Step, e.Name, formatDuration(e.Duration), e.Err)
+	}
+	return b.String()
+}
+
+// Finish stops the live view, restores logging and prints a summary of every step.
+func (p *Progress) Finish() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+		logging.Default().SetLevel(p.level)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.live && p.drawn > 0 {
+		p.redraw()
+	}
+	if len(p.steps) == 0 {
+		return
+	}
+
+	steps := slices.Clone(p.steps)
+	slices.SortStableFunc(steps, func(a, b txman.StepEvent) int {
+		if c := strings.Compare(a.Host, b.Host); c != 0 {
+			return c
+		}
+		return a.Step - b.Step
+	})
+	fmt.Fprintln(p.out)
+	w := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tSTEP\tNAME\tSTATUS\tDURATION")
+	for _, s := range steps {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", s.Host, s.Step, s.Name, s.State, formatDuration(s.Duration))
+	}
+	w.Flush()
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,53 @@
+package cliutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/logging"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+var ErrNotInteractive = errors.New("confirmation required, but stdin is not a terminal")
+
+// Confirm asks a yes/no question on stdout and reads the answer from stdin.
+// Anything but "y" or "yes" is treated as no.
+func Confirm(question string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, ErrNotInteractive
+	}
+
+	fmt.Printf("%s [y/N]: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// hostKeyConfirmFunc returns a callback for sshexec that either trusts
+// every new host key or asks the user about each of them.
+func hostKeyConfirmFunc(acceptAll bool) sshexec.HostKeyConfirmFunc {
+	return func(host string, key ssh.PublicKey) (bool, error) {
+		fingerprint := ssh.FingerprintSHA256(key)
+		if acceptAll {
+			logging.WarnHostf(host, "adding %s key %s to known_hosts", key.Type(), fingerprint)
+			return true, nil
+		}
+
+		fmt.Printf("The authenticity of host %s can't be established.\n", host)
+		fmt.Printf("%s key fingerprint is %s.\n", key.Type(), fingerprint)
+		ok, err := Confirm("Are you sure you want to continue connecting?")
+		if errors.Is(err, ErrNotInteractive) {
+			return false, fmt.Errorf("host %s is not in known_hosts, use --accept-host-keys to trust it", host)
+		}
+		return ok, err
+	}
+}
@@ -2,23 +2,74 @@ package deploy
 
 import (
 	"context"
+	"errors"
+	"os"
+	"strings"
 
+	"github.com/lex-unix/faino/internal/app"
 	"github.com/lex-unix/faino/internal/cli/cliutil"
 	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/txman"
 	"github.com/spf13/cobra"
 )
 
+type DeployOptions struct {
+	SkipPreflight bool
+	Note          string
+	Events        string
+}
+
 func NewCmdDeploy(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	opts := DeployOptions{}
 	cmd := &cobra.Command{
 		Use:   "deploy",
 		Short: "Deploy your app to the servers",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			app, err := f.App()
+			cfg, err := f.Config()
 			if err != nil {
 				return err
 			}
+			if opts.SkipPreflight {
+				cfg.Preflight.Skip = true
+			}
 
-			if err := app.Deploy(ctx); err != nil {
+			a, err := f.App()
+			if err != nil {
+				return err
+			}
+
+			if opts.Events != "" {
+				events, err := cliutil.ObserveEvents(a, opts.Events)
+				if err != nil {
+					return err
+				}
+				defer events.Close()
+			}
+
+			progress := cliutil.NewProgress(os.Stdout)
+			a.SetProgress(progress.Update)
+			err = a.Deploy(ctx, app.DeployOptions{Note: opts.Note})
+			progress.Finish()
+			if err != nil {
+				var preflightErr *app.PreflightError
+				if errors.As(err, &preflightErr) {
+					cliutil.PrintPreflightReport(preflightErr.Report, os.Stdout)
+				}
+				var rollbackErr *txman.RollbackError
+				if errors.As(err, &rollbackErr) {
+					cliutil.PrintRollbackReport(rollbackErr.Report, os.Stdout)
+					return err
+				}
+				// deploy met its quorum and failed servers were rolled back
+				var partialErr *txman.PartialError
+				if errors.As(err, &partialErr) {
+					logging.Warnf("app deployed to %d of %d servers, failed on %s", partialErr.Hosts-len(partialErr.Failed), partialErr.Hosts, strings.Join(partialErr.FailedHosts(), ", "))
+					var hookErr *app.PostDeployError
+					if errors.As(err, &hookErr) {
+						return hookErr
+					}
+					return nil
+				}
 				return err
 			}
 			logging.Info("app deployed to servers")
@@ -26,5 +77,9 @@ func NewCmdDeploy(ctx context.Context, f *cliutil.Factory) *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&opts.SkipPreflight, "skip-preflight", false, "Deploy without checking servers first")
+	cmd.Flags().StringVar(&opts.Note, "note", "", "Note stored in history of the deployed version")
+	cmd.Flags().StringVar(&opts.Events, "events", "", "Write transaction events to file as JSON lines")
+
 	return cmd
 }
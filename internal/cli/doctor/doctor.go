@@ -0,0 +1,39 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/lex-unix/faino/internal/cli/cliutil"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+func NewCmdDoctor(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that local machine and servers are ready for deploy",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app, err := f.App()
+			if err != nil {
+				return err
+			}
+
+			report, err := app.Preflight(ctx)
+			if err != nil {
+				return err
+			}
+
+			cliutil.PrintPreflightReport(report, os.Stdout)
+			if report.Failed() {
+				return errors.New("one or more checks failed")
+			}
+
+			logging.Info("all checks passed")
+			return nil
+		},
+	}
+
+	return cmd
+}
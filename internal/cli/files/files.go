@@ -0,0 +1,20 @@
+package files
+
+import (
+	"context"
+
+	"github.com/lex-unix/faino/internal/cli/cliutil"
+	pushCmd "github.com/lex-unix/faino/internal/cli/files/push"
+	"github.com/spf13/cobra"
+)
+
+func NewCmdFiles(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "files",
+		Short: "Manage files uploaded to servers",
+	}
+
+	cmd.AddCommand(pushCmd.NewCmdPush(ctx, f))
+
+	return cmd
+}
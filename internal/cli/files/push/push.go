@@ -0,0 +1,29 @@
+package push
+
+import (
+	"context"
+
+	"github.com/lex-unix/faino/internal/cli/cliutil"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+func NewCmdPush(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "push",
+		Short: "Upload files from config to servers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app, err := f.App()
+			if err != nil {
+				return err
+			}
+			if err := app.PushFiles(ctx); err != nil {
+				return err
+			}
+			logging.Info("files uploaded to servers")
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -3,40 +3,92 @@ package history
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/lex-unix/faino/internal/app"
 	"github.com/lex-unix/faino/internal/cli/cliutil"
 	"github.com/spf13/cobra"
 )
 
+type HistoryOptions struct {
+	sort   string
+	since  time.Duration
+	limit  int
+	status string
+}
+
 func NewCmdHistory(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	opts := HistoryOptions{}
 	cmd := &cobra.Command{
 		Use:   "history",
 		Short: "List app version history",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			sortDir, _ := cmd.Flags().GetString("sort")
-			if !slices.Contains([]string{"asc", "desc"}, sortDir) {
-				return fmt.Errorf("sort value can be either 'desc' or 'asc' and you passed: %s", sortDir)
+			if !slices.Contains([]string{"asc", "desc"}, opts.sort) {
+				return fmt.Errorf("sort value can be either 'desc' or 'asc' and you passed: %s", opts.sort)
+			}
+			if opts.status != "" && !slices.Contains(app.HistoryStatuses, opts.status) {
+				return fmt.Errorf("status can be one of %s and you passed: %s", strings.Join(app.HistoryStatuses, ", "), opts.status)
 			}
-			app, err := f.App()
+
+			a, err := f.App()
 			if err != nil {
 				return err
 			}
 
-			history, err := app.History(ctx, sortDir)
+			filter := app.HistoryFilter{
+				Status: opts.status,
+				Limit:  opts.limit,
+				Sort:   opts.sort,
+			}
+			if opts.since > 0 {
+				filter.Since = time.Now().Add(-opts.since)
+			}
+
+			history, err := a.History(ctx, filter)
 			if err != nil {
 				return err
 			}
 
-			for _, entry := range history {
-				fmt.Printf("Version: %s, date: %s\n", entry.Version, entry.Timestamp.Format("2006-01-02 15:04:05"))
-			}
+			printHistory(history, os.Stdout)
 
 			return nil
 		},
 	}
 
-	cmd.PersistentFlags().StringP("sort", "s", "desc", "Display history sorted by timestamp in (desc)ending or (asc)ending order.")
+	cmd.PersistentFlags().StringVarP(&opts.sort, "sort", "s", "desc", "Display history sorted by timestamp in (desc)ending or (asc)ending order.")
+	cmd.Flags().DurationVar(&opts.since, "since", 0, "Show versions deployed within duration, e.g. 168h")
+	cmd.Flags().IntVarP(&opts.limit, "limit", "n", 0, "Show at most n most recent versions, 0 shows all")
+	cmd.Flags().StringVar(&opts.status, "status", "", "Show only versions with status: active, inactive, rolled-back or failed")
 
 	return cmd
 }
+
+func printHistory(history []app.HistoryEntry, out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATUS\tDEPLOYED\tACTIVATED\tDEPLOYER\tCOMMIT\tNOTES")
+	for _, h := range history {
+		activated := "-"
+		if !h.ActivatedAt.IsZero() {
+			activated = h.ActivatedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		status := h.Status
+		if h.Partial() {
+			status = fmt.Sprintf("%s (failed on %s)", status, strings.Join(h.FailedHosts, ", "))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			h.Version,
+			status,
+			h.DeployedAt.Local().Format("2006-01-02 15:04:05"),
+			activated,
+			cliutil.Dash(h.Deployer),
+			cliutil.Dash(h.Commit),
+			h.Notes,
+		)
+	}
+	w.Flush()
+}
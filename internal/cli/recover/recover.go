@@ -0,0 +1,91 @@
+package recover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lex-unix/faino/internal/cli/cliutil"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/txman"
+	"github.com/spf13/cobra"
+)
+
+type RecoverOptions struct {
+	forward bool
+	events  string
+}
+
+func NewCmdRecover(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	opts := RecoverOptions{}
+	cmd := &cobra.Command{
+		Use:   "recover [TRANSACTION]",
+		Short: "Recover transactions interrupted by a crash",
+		Long: `Without arguments, list transactions that were interrupted before they were committed or rolled back.
+With TRANSACTION, roll it back by replaying recorded rollback commands on every host, or commit it with --forward.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			app, err := f.App()
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				journals, err := app.PendingTransactions(ctx)
+				if err != nil {
+					return err
+				}
+				if len(journals) == 0 {
+					logging.Info("no interrupted transactions")
+					return nil
+				}
+				printJournals(journals, os.Stdout)
+				return nil
+			}
+
+			id := args[0]
+			if opts.events != "" {
+				events, err := cliutil.ObserveEvents(app, opts.events)
+				if err != nil {
+					return err
+				}
+				defer events.Close()
+			}
+
+			if err := app.Recover(ctx, id, opts.forward); err != nil {
+				var rollbackErr *txman.RollbackError
+				if errors.As(err, &rollbackErr) {
+					cliutil.PrintRollbackReport(rollbackErr.Report, os.Stdout)
+				}
+				return err
+			}
+			if opts.forward {
+				logging.Infof("transaction %s committed", id)
+			} else {
+				logging.Infof("transaction %s rolled back", id)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.forward, "forward", false, "Commit transaction instead of rolling it back, only if it completed on every host")
+	cmd.Flags().StringVar(&opts.events, "events", "", "Write rollback events to file as JSON lines")
+
+	return cmd
+}
+
+func printJournals(journals []txman.Journal, out io.Writer) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TRANSACTION\tHOST\tSTARTED\tSTEPS\tSTATE")
+	for _, j := range journals {
+		state := "interrupted"
+		if j.Completed {
+			state = "completed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", j.ID, j.Host, j.StartedAt.Local().Format("2006-01-02 15:04:05"), len(j.Steps), state)
+	}
+	w.Flush()
+}
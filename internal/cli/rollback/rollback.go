@@ -2,35 +2,116 @@ package rollback
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 
+	"github.com/lex-unix/faino/internal/app"
 	"github.com/lex-unix/faino/internal/cli/cliutil"
 	"github.com/lex-unix/faino/internal/logging"
+	"github.com/lex-unix/faino/internal/txman"
 	"github.com/spf13/cobra"
 )
 
+type RollbackOptions struct {
+	steps  int
+	yes    bool
+	events string
+}
+
 func NewCmdRollback(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	opts := RollbackOptions{}
 	cmd := &cobra.Command{
-		Use:   "rollback VERSION",
+		Use:   "rollback [VERSION]",
 		Short: "Rollback to your app's desired version",
+		Long:  "Rollback to VERSION, or to the previously active version if VERSION is omitted.",
+		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 {
-				return fmt.Errorf("the version of the app to rollback to is required")
+			var version string
+			if len(args) > 0 {
+				version = args[0]
+			}
+			if version != "" && cmd.Flags().Changed("steps") {
+				return fmt.Errorf("VERSION and --steps cannot be used together")
 			}
-			version := args[0]
 
-			app, err := f.App()
+			a, err := f.App()
 			if err != nil {
 				return err
 			}
 
-			if err := app.Rollback(ctx, version); err != nil {
+			plan, err := a.PlanRollback(ctx, version, opts.steps)
+			if err != nil {
 				return err
 			}
-			logging.Infof("app rolled back to version %s", version)
+
+			printPlan(plan, os.Stdout)
+			// an explicit VERSION is already a decision, only a target
+			// picked from history has to be confirmed
+			if version == "" && !opts.yes {
+				ok, err := cliutil.Confirm("Continue with rollback?")
+				if errors.Is(err, cliutil.ErrNotInteractive) {
+					return fmt.Errorf("rollback must be confirmed, use --yes to skip confirmation")
+				}
+				if err != nil {
+					return err
+				}
+				if !ok {
+					logging.Info("rollback canceled")
+					return nil
+				}
+			}
+
+			if opts.events != "" {
+				events, err := cliutil.ObserveEvents(a, opts.events)
+				if err != nil {
+					return err
+				}
+				defer events.Close()
+			}
+
+			progress := cliutil.NewProgress(os.Stdout)
+			a.SetProgress(progress.Update)
+			err = a.Rollback(ctx, plan.Target.Version)
+			progress.Finish()
+			if err != nil {
+				var rollbackErr *txman.RollbackError
+				if errors.As(err, &rollbackErr) {
+					cliutil.PrintRollbackReport(rollbackErr.Report, os.Stdout)
+				}
+				return err
+			}
+			logging.Infof("app rolled back to version %s", plan.Target.Version)
 			return nil
 		},
 	}
 
+	cmd.Flags().IntVarP(&opts.steps, "steps", "n", 1, "Roll back to the version that was active n versions ago")
+	cmd.Flags().BoolVarP(&opts.yes, "yes", "y", false, "Roll back to a version picked from history without confirmation")
+	cmd.Flags().StringVar(&opts.events, "events", "", "Write transaction events to file as JSON lines")
+
 	return cmd
 }
+
+func printPlan(plan app.RollbackPlan, out io.Writer) {
+	if plan.Current.Version != "" {
+		fmt.Fprintf(out, "Current version: %s\n", describe(plan.Current))
+	}
+	fmt.Fprintf(out, "Roll back to:    %s\n", describe(plan.Target))
+}
+
+func describe(h app.HistoryEntry) string {
+	s := fmt.Sprintf("%s (deployed %s", h.Version, h.DeployedAt.Local().Format("2006-01-02 15:04:05"))
+	if h.Deployer != "" {
+		s += " by " + h.Deployer
+	}
+	if h.Commit != "" {
+		s += ", commit " + h.Commit
+	}
+	s += ")"
+	if h.Notes != "" {
+		s += ": " + h.Notes
+	}
+	return s
+}
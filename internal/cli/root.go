@@ -5,15 +5,20 @@ import (
 	"os"
 
 	appCmd "github.com/lex-unix/faino/internal/cli/app"
+	auditCmd "github.com/lex-unix/faino/internal/cli/audit"
 	"github.com/lex-unix/faino/internal/cli/cliutil"
 	deployCmd "github.com/lex-unix/faino/internal/cli/deploy"
+	doctorCmd "github.com/lex-unix/faino/internal/cli/doctor"
+	filesCmd "github.com/lex-unix/faino/internal/cli/files"
 	historyCmd "github.com/lex-unix/faino/internal/cli/history"
 	initCmd "github.com/lex-unix/faino/internal/cli/init"
 	logsCmd "github.com/lex-unix/faino/internal/cli/logs"
 	proxyCmd "github.com/lex-unix/faino/internal/cli/proxy"
+	recoverCmd "github.com/lex-unix/faino/internal/cli/recover"
 	registryCmd "github.com/lex-unix/faino/internal/cli/registry"
 	rollbackCmd "github.com/lex-unix/faino/internal/cli/rollback"
 	setupCmd "github.com/lex-unix/faino/internal/cli/setup"
+	sshCmd "github.com/lex-unix/faino/internal/cli/ssh"
 	versionCmd "github.com/lex-unix/faino/internal/cli/version"
 	"github.com/lex-unix/faino/internal/config"
 	"github.com/lex-unix/faino/internal/logging"
@@ -47,16 +52,22 @@ func NewRootCmd(ctx context.Context, f *cliutil.Factory, version string) *cobra.
 	cmd.PersistentFlags().BoolP("debug", "d", false, "Display debugging output in the console")
 	cmd.PersistentFlags().String("host", "", "Host to run command on")
 	cmd.PersistentFlags().Bool("force", false, "Force non-transactional execution")
+	cmd.PersistentFlags().Bool("accept-host-keys", false, "Add unknown host keys to known_hosts without confirmation")
 
 	cmd.AddCommand(deployCmd.NewCmdDeploy(ctx, f))
 	cmd.AddCommand(rollbackCmd.NewCmdRollback(ctx, f))
 	cmd.AddCommand(historyCmd.NewCmdHistory(ctx, f))
+	cmd.AddCommand(auditCmd.NewCmdAudit(ctx, f))
 	cmd.AddCommand(logsCmd.NewCmdLogs(ctx, f))
 	cmd.AddCommand(appCmd.NewCmdApp(ctx, f))
 	cmd.AddCommand(registryCmd.NewCmdRegistry(ctx, f))
 	cmd.AddCommand(proxyCmd.NewCmdProxy(ctx, f))
 	cmd.AddCommand(initCmd.NewCmdInit(ctx, f))
 	cmd.AddCommand(setupCmd.NewCmdSetup(ctx, f))
+	cmd.AddCommand(sshCmd.NewCmdSSH(ctx, f))
+	cmd.AddCommand(filesCmd.NewCmdFiles(ctx, f))
+	cmd.AddCommand(doctorCmd.NewCmdDoctor(ctx, f))
+	cmd.AddCommand(recoverCmd.NewCmdRecover(ctx, f))
 	cmd.AddCommand(versionCmd.NewCmdVersion())
 
 	return cmd
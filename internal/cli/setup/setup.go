@@ -2,23 +2,49 @@ package setup
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
 
+	"github.com/lex-unix/faino/internal/app"
 	"github.com/lex-unix/faino/internal/cli/cliutil"
 	"github.com/lex-unix/faino/internal/logging"
 	"github.com/spf13/cobra"
 )
 
+type SetupOptions struct {
+	InstallDocker bool
+	InstallMethod string
+}
+
 func NewCmdSetup(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	opts := SetupOptions{}
 	cmd := &cobra.Command{
 		Use:   "setup",
 		Short: "Setup serverse with needed directories",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			app, err := f.App()
+			if !slices.Contains([]string{app.InstallMethodPackage, app.InstallMethodScript}, opts.InstallMethod) {
+				return fmt.Errorf("install method can be either 'package' or 'script' and you passed: %s", opts.InstallMethod)
+			}
+
+			cfg, err := f.Config()
+			if err != nil {
+				return err
+			}
+			a, err := f.App()
 			if err != nil {
 				return err
 			}
 			logging.Info("setting up servers")
-			if err := app.Setup(ctx); err != nil {
+			report, err := a.Setup(ctx, app.SetupOptions{
+				InstallDocker: opts.InstallDocker,
+				InstallMethod: opts.InstallMethod,
+				User:          cfg.SSH.User,
+			})
+			printReport(report, os.Stdout)
+			if err != nil {
 				return err
 			}
 			logging.Info("setup completed successfully")
@@ -26,5 +52,43 @@ func NewCmdSetup(ctx context.Context, f *cliutil.Factory) *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&opts.InstallDocker, "install-docker", false, "Install and start Docker on servers where it is missing")
+	cmd.Flags().StringVar(&opts.InstallMethod, "install-method", app.InstallMethodPackage, "Install Docker with distro (package) manager or official (script) from get.docker.com")
+
 	return cmd
 }
+
+func printReport(report app.SetupReport, out io.Writer) {
+	if len(report) == 0 {
+		return
+	}
+
+	hosts := make([]string, 0, len(report))
+	for host := range report {
+		hosts = append(hosts, host)
+	}
+	slices.Sort(hosts)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tDOCKER\tRUNNING\tSTATUS")
+	for _, host := range hosts {
+		r := report[host]
+		installed := yesNo(r.DockerInstalled)
+		if r.DockerInstalledNow {
+			installed = "installed"
+		}
+		status := "ready"
+		if r.Err != nil {
+			status = r.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", host, installed, yesNo(r.DockerRunning), status)
+	}
+	w.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
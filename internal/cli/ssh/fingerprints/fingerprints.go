@@ -0,0 +1,57 @@
+package fingerprints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lex-unix/faino/internal/cli/cliutil"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/spf13/cobra"
+)
+
+func NewCmdFingerprints(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fingerprints",
+		Short: "Show host key fingerprints of servers and whether they are in known_hosts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := f.Config()
+			if err != nil {
+				return err
+			}
+
+			hosts := cfg.Servers
+			if cfg.Host != "" {
+				hosts = []string{cfg.Host}
+			}
+
+			var scanErr error
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "HOST\tTYPE\tFINGERPRINT\tSTATUS")
+			for _, host := range hosts {
+				// local server is not reached over SSH
+				if host == sshexec.LocalHost {
+					continue
+				}
+				info, err := sshexec.ScanHostKey(host, cfg.SSH.Port)
+				if err != nil {
+					logging.ErrorHost(host, err.Error())
+					scanErr = errors.Join(scanErr, err)
+					continue
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Host, info.Type, info.Fingerprint, info.Status)
+			}
+
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			return scanErr
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,20 @@
+package ssh
+
+import (
+	"context"
+
+	"github.com/lex-unix/faino/internal/cli/cliutil"
+	fingerprintsCmd "github.com/lex-unix/faino/internal/cli/ssh/fingerprints"
+	"github.com/spf13/cobra"
+)
+
+func NewCmdSSH(ctx context.Context, f *cliutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ssh",
+		Short: "Manage SSH connections to servers",
+	}
+
+	cmd.AddCommand(fingerprintsCmd.NewCmdFingerprints(ctx, f))
+
+	return cmd
+}
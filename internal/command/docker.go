@@ -2,10 +2,31 @@ package command
 
 import (
 	"fmt"
+
+	"al.essio.dev/pkg/shellescape"
 )
 
-func CreateNetwork() string {
-	return "docker network create faino"
+// Network connects the proxy and app containers on servers.
+const Network = "faino"
+
+func (r Runtime) CreateNetwork() string {
+	return r.Container("network create", Network)
+}
+
+func (r Runtime) InspectNetwork() string {
+	return r.Container("network inspect", Network)
+}
+
+func (r Runtime) InspectContainer(container string) string {
+	return r.Container("container inspect", container)
+}
+
+func (r Runtime) InspectImage(img string) string {
+	return r.Container("image inspect", img)
+}
+
+func IsBuildxInstalled() string {
+	return "docker buildx version"
 }
 
 func IsDockerInstalled() string {
@@ -16,6 +37,16 @@ func IsDockerRunning() string {
 	return "docker version"
 }
 
+// IsRuntimeInstalled checks that the current runtime is installed on the server.
+func (r Runtime) IsRuntimeInstalled() string {
+	return r.Container("-v")
+}
+
+// IsRuntimeRunning checks that the current runtime can run containers on the server.
+func (r Runtime) IsRuntimeRunning() string {
+	return r.Container("version")
+}
+
 func TagImage(img, registryImg string) string {
 	return fmt.Sprintf("docker tag %s %s", img, registryImg)
 }
@@ -24,58 +55,120 @@ func PushImage(img string) string {
 	return fmt.Sprintf("docker push %s", img)
 }
 
-func PullImage(img string) string {
-	return fmt.Sprintf("docker pull %s", img)
+func (r Runtime) PullImage(img string) string {
+	return r.Container("pull", r.qualifyImage(img))
+}
+
+func (r Runtime) StartContainer(img string) string {
+	return r.Container("start", img)
+}
+
+func (r Runtime) RemoveContainer(container string) string {
+	return r.Container("rm", container)
 }
 
-func StartContainer(img string) string {
-	return fmt.Sprintf("docker start %s", img)
+func (r Runtime) RunContainer(img, container, service string, env map[string]string, volumes []string) string {
+	return r.appContainer("run -d", img, container, service, env, volumes)
 }
 
-func RemoveContainer(container string) string {
-	return fmt.Sprintf("docker rm %s", container)
+// CreateContainer creates container of the app without starting it, it is
+// started later by StartContainer.
+func (r Runtime) CreateContainer(img, container, service string, env map[string]string, volumes []string) string {
+	return r.appContainer("create", img, container, service, env, volumes)
 }
 
-func RunContainer(img, container, service string, env map[string]string, volumes []string) string {
-	return Docker(
-		"run -d --network faino --restart unless-stopped",
+func (r Runtime) appContainer(action, img, container, service string, env map[string]string, volumes []string) string {
+	return r.Container(
+		action,
+		"--network faino",
+		r.restartPolicy(),
 		expandEnv(env),
 		"--label traefik.enable=true",
 		fmt.Sprintf("--label traefik.http.routers.%s.entrypoints=web", service),
 		fmt.Sprintf("--label traefik.http.routers.%s.rule='PathPrefix(`/`)'", service),
 		expandVolumes(volumes),
 		"--name", container,
+		r.qualifyImage(img),
+	)
+}
+
+// RunOneOff runs cmd in a throwaway container that is removed when cmd exits.
+func (r Runtime) RunOneOff(img string, env map[string]string, volumes []string, cmd string, interactive bool) string {
+	return r.Container(
+		"run --rm --network faino",
+		when(interactive, "-it"),
+		expandEnv(env),
+		expandVolumes(volumes),
 		img,
+		"sh -c",
+		shellescape.Quote(cmd),
 	)
 }
 
-func StopContainer(container string) string {
-	return fmt.Sprintf("docker stop %s || true", container)
+// RunCronJob runs cmd in a throwaway container, reading env from envFile
+// so that secrets do not end up in crontab.
+func (r Runtime) RunCronJob(img string, envFile string, volumes []string, job string, cmd string) string {
+	return r.Container(
+		"run --rm --network faino",
+		"--env-file", envFile,
+		fmt.Sprintf("--label faino.cron=%s", job),
+		expandVolumes(volumes),
+		img,
+		"sh -c",
+		shellescape.Quote(cmd),
+	)
 }
 
-func RunProxy(img string, container string, labels map[string]any, args map[string]any) string {
-	return Docker(
-		"run -d -p 80:80 --volume /var/run/docker.sock:/var/run/docker.sock:ro",
-		"--network faino --restart unless-stopped",
+// HealthCheckHTTP requests path on port of container from the host. Container is
+// reached by its address in faino network, so it does not need to publish ports.
+// On podman the request is sent from a throwaway container in faino network.
+func (r Runtime) HealthCheckHTTP(container string, port int, path string) string {
+	if r.podman() {
+		return r.Container(
+			"run --rm --network faino",
+			curlImage,
+			fmt.Sprintf("-fsS -o /dev/null --max-time 5 %s", shellescape.Quote(fmt.Sprintf("http://%s:%d%s", container, port, path))),
+		)
+	}
+	return fmt.Sprintf(
+		`curl -fsS -o /dev/null --max-time 5 "http://$(docker inspect -f '{{(index .NetworkSettings.Networks "faino").IPAddress}}' %s):%d%s"`,
+		container, port, path,
+	)
+}
+
+// HealthCheckCommand runs cmd inside container.
+func (r Runtime) HealthCheckCommand(container string, cmd string) string {
+	return r.Container("exec", container, "sh -c", shellescape.Quote(cmd))
+}
+
+func (r Runtime) StopContainer(container string) string {
+	return r.Container("stop", container, "|| true")
+}
+
+func (r Runtime) RunProxy(img string, container string, labels map[string]any, args map[string]any) string {
+	return r.Container(
+		"run -d -p 80:80",
+		"--network faino",
+		r.restartPolicy(),
 		"--name", container,
-		"--volume /var/run/docker.sock:/var/run/docker.sock:ro",
+		fmt.Sprintf("--volume %s:/var/run/docker.sock:ro", r.runtimeSocket()),
 		expandLabels(labels),
-		img,
+		r.qualifyImage(img),
 		"--providers.docker --entryPoints.web.address=:80 --accesslog=true",
 		formatArgs(args),
 	)
 }
 
-func ListRunningContainers() string {
-	return "docker ps"
+func (r Runtime) ListRunningContainers() string {
+	return r.Container("ps")
 }
 
-func ListAllContainers() string {
-	return "docker ps -a"
+func (r Runtime) ListAllContainers() string {
+	return r.Container("ps -a")
 }
 
-func ContainerLogs(container string, follow bool, lines int, since string) string {
-	return Docker(
+func (r Runtime) ContainerLogs(container string, follow bool, lines int, since string) string {
+	return r.Container(
 		"logs",
 		when(since != "", fmt.Sprintf("--since %s", since)),
 		when(lines > 0, fmt.Sprintf("--tail %d", lines)),
@@ -84,8 +177,8 @@ func ContainerLogs(container string, follow bool, lines int, since string) strin
 	)
 }
 
-func RegistryLogin(registry, user string) string {
-	return Docker(
+func (r Runtime) RegistryLogin(registry, user string) string {
+	return r.Container(
 		"login",
 		registry,
 		"-u",
@@ -94,12 +187,12 @@ func RegistryLogin(registry, user string) string {
 	)
 }
 
-func RegistryLogout() string {
-	return "docker logout"
+func (r Runtime) RegistryLogout() string {
+	return r.Container("logout")
 }
 
-func Exec(container string, execCmd string, interactive bool) string {
-	return Docker(
+func (r Runtime) Exec(container string, execCmd string, interactive bool) string {
+	return r.Container(
 		"exec",
 		when(interactive, "-it"),
 		container,
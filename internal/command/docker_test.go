@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -43,7 +44,7 @@ func TestContainerLogs(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := ContainerLogs(
+			got := RuntimeDocker.ContainerLogs(
 				tt.args.container,
 				tt.args.follow,
 				tt.args.lines,
@@ -95,7 +96,7 @@ func TestRunContainer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := RunContainer(tt.args.image, tt.args.container, tt.args.service, tt.args.env, tt.args.volumes)
+			got := RuntimeDocker.RunContainer(tt.args.image, tt.args.container, tt.args.service, tt.args.env, tt.args.volumes)
 
 			if len(tt.expectedEnv) == 0 {
 				assert.NotContains(t, got, "--env")
@@ -115,3 +116,37 @@ func TestRunContainer(t *testing.T) {
 		})
 	}
 }
+
+func TestRunOneOff(t *testing.T) {
+	got := RuntimeDocker.RunOneOff(
+		"test-image",
+		map[string]string{"VAR1": "VAL1"},
+		[]string{"src/volume-1:/dst/volume-1"},
+		"bin/migrate && echo 'done'",
+		false,
+	)
+
+	assert.Contains(t, got, "docker run --rm --network faino")
+	assert.Contains(t, got, "--env VAR1=VAL1")
+	assert.Contains(t, got, "--volume src/volume-1:/dst/volume-1")
+	assert.NotContains(t, got, "-it")
+	assert.Contains(t, got, `test-image sh -c 'bin/migrate && echo '"'"'done'"'"''`)
+}
+
+func TestHealthCheckHTTP(t *testing.T) {
+	got := RuntimeDocker.HealthCheckHTTP("app-v1", 3000, "/up")
+
+	assert.Contains(t, got, "curl -fsS -o /dev/null --max-time 5")
+	assert.Contains(t, got, `docker inspect -f '{{(index .NetworkSettings.Networks "faino").IPAddress}}' app-v1`)
+	assert.Contains(t, got, ":3000/up\"")
+}
+
+func TestCreateContainer(t *testing.T) {
+	got := RuntimeDocker.CreateContainer("app:v1", "app-v1", "app", map[string]string{"KEY": "value"}, []string{"src:/dst"})
+
+	assert.True(t, strings.HasPrefix(got, "docker create --network faino --restart unless-stopped"))
+	assert.Contains(t, got, "--env KEY=value")
+	assert.Contains(t, got, "--volume src:/dst")
+	assert.Contains(t, got, "--name app-v1 app:v1")
+	assert.NotContains(t, got, " -d ")
+}
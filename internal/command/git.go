@@ -9,3 +9,7 @@ func CommitHash() string {
 func CommitMessage() string {
 	return fmt.Sprintf("git log -1 --pretty=%%B")
 }
+
+func GitUserName() string {
+	return "git config user.name"
+}
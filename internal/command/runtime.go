@@ -0,0 +1,70 @@
+package command
+
+import "strings"
+
+// Runtime is the container engine that runs the app on servers. Images are
+// always built locally with Docker. Commands run on servers are built by its methods.
+type Runtime string
+
+const (
+	RuntimeDocker Runtime = "docker"
+	RuntimePodman Runtime = "podman"
+)
+
+// Runtimes lists every supported runtime.
+var Runtimes = []Runtime{RuntimeDocker, RuntimePodman}
+
+// curlImage runs health checks on podman, where container addresses are not
+// reachable from rootless hosts.
+const curlImage = "docker.io/curlimages/curl:latest"
+
+// ParseRuntime returns runtime named name, Docker if name is empty.
+func ParseRuntime(name string) Runtime {
+	if name == "" {
+		return RuntimeDocker
+	}
+	return Runtime(name)
+}
+
+func (r Runtime) podman() bool {
+	return r == RuntimePodman
+}
+
+// Container is like Docker, but builds a command of the runtime. It is
+// used for commands run on servers.
+func (r Runtime) Container(args ...string) string {
+	return join(string(r), args)
+}
+
+// restartPolicy keeps the container running across reboots. Podman has no
+// daemon, containers with restart policy always are started by podman-restart.service.
+func (r Runtime) restartPolicy() string {
+	if r.podman() {
+		return "--restart always"
+	}
+	return "--restart unless-stopped"
+}
+
+// runtimeSocket is the API socket of the runtime on the server, mounted into the proxy.
+func (r Runtime) runtimeSocket() string {
+	if r.podman() {
+		return `"$(if [ "$(id -u)" -eq 0 ]; then echo /run/podman/podman.sock; else echo "${XDG_RUNTIME_DIR:-/run/user/$(id -u)}/podman/podman.sock"; fi)"`
+	}
+	return "/var/run/docker.sock"
+}
+
+// qualifyImage prefixes short image names with docker.io on podman, which
+// refuses to guess the registry of short names without a terminal.
+func (r Runtime) qualifyImage(img string) string {
+	if !r.podman() {
+		return img
+	}
+	first, _, found := strings.Cut(img, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return img
+	}
+	if !found {
+		return "docker.io/library/" + img
+	}
+	return "docker.io/" + img
+}
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQualifyImage(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "traefik:v3.1", want: "docker.io/library/traefik:v3.1"},
+		{image: "user/app:v1", want: "docker.io/user/app:v1"},
+		{image: "ghcr.io/user/app:v1", want: "ghcr.io/user/app:v1"},
+		{image: "localhost/app", want: "localhost/app"},
+		{image: "registry:5000/app", want: "registry:5000/app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.want, RuntimePodman.qualifyImage(tt.image))
+		})
+	}
+}
+
+func TestPodmanCommands(t *testing.T) {
+	run := RuntimePodman.RunContainer("user/app:v1", "app-v1", "app", nil, nil)
+	assert.True(t, strings.HasPrefix(run, "podman run -d --network faino --restart always"))
+	assert.Contains(t, run, "docker.io/user/app:v1")
+
+	health := RuntimePodman.HealthCheckHTTP("app-v1", 3000, "/up")
+	assert.True(t, strings.HasPrefix(health, "podman run --rm --network faino "+curlImage))
+	assert.Contains(t, health, "http://app-v1:3000/up")
+	assert.NotContains(t, health, "docker inspect")
+
+	proxy := RuntimePodman.RunProxy("traefik:v3.1", "traefik", nil, nil)
+	assert.Contains(t, proxy, "podman/podman.sock")
+	assert.Equal(t, 1, strings.Count(proxy, "--volume"))
+
+	assert.Equal(t, "docker ps", Docker("ps"))
+	assert.Equal(t, "podman ps", RuntimePodman.Container("ps"))
+}
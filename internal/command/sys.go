@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"strings"
 
 	"al.essio.dev/pkg/shellescape"
 )
@@ -13,3 +14,97 @@ func Mkdir(dir string) string {
 func CreateFileWithContents(file string, contents string) string {
 	return fmt.Sprintf("echo %s > %s", shellescape.Quote(contents), file)
 }
+
+func RemoveFile(file string) string {
+	return fmt.Sprintf("rm -f %s", file)
+}
+
+func RemoveDir(dir string) string {
+	return fmt.Sprintf("rm -rf %s", dir)
+}
+
+// AppendToFile appends line to file, which is created or kept readable only by its owner.
+func AppendToFile(file string, line string) string {
+	return fmt.Sprintf("umask 077 && printf '%%s\\n' %s >> %s && chmod 600 %s", shellescape.Quote(line), file, file)
+}
+
+// DiskFree reports disk usage in POSIX format, with sizes in kilobytes, of the
+// filesystem containing dir. If dir does not exist, root filesystem is used.
+func DiskFree(dir string) string {
+	return fmt.Sprintf("df -Pk %s 2>/dev/null || df -Pk /", shellescape.Quote(dir))
+}
+
+// ReadCrontab prints crontab of the current user, or nothing if there is none.
+func ReadCrontab() string {
+	return "crontab -l 2>/dev/null || true"
+}
+
+// InstallCrontab replaces crontab of the current user with contents read from stdin.
+func InstallCrontab() string {
+	return "crontab -"
+}
+
+// InstallCrontabFile replaces crontab of the current user with contents of file.
+func InstallCrontabFile(file string) string {
+	return fmt.Sprintf("crontab %s", file)
+}
+
+// RestoreFile copies backup over file. If there is no backup, file did not exist
+// before and is removed.
+func RestoreFile(backup, file string) string {
+	return fmt.Sprintf("if [ -e %[1]s ]; then cp -p %[1]s %[2]s; else rm -f %[2]s; fi", backup, file)
+}
+
+// AsRoot runs cmd as root, using sudo if the SSH user is not root.
+func AsRoot(cmd string) string {
+	quoted := shellescape.Quote(cmd)
+	return fmt.Sprintf(`if [ "$(id -u)" -eq 0 ]; then sh -c %s; else sudo sh -c %s; fi`, quoted, quoted)
+}
+
+// InstallDockerPackage installs Docker with the package manager found on the host.
+func InstallDockerPackage() string {
+	return AsRoot(strings.Join([]string{
+		"if command -v apt-get >/dev/null 2>&1; then apt-get update && apt-get install -y docker.io;",
+		"elif command -v dnf >/dev/null 2>&1; then dnf install -y docker;",
+		"elif command -v yum >/dev/null 2>&1; then yum install -y docker;",
+		"elif command -v zypper >/dev/null 2>&1; then zypper --non-interactive install docker;",
+		"elif command -v apk >/dev/null 2>&1; then apk add docker;",
+		"elif command -v pacman >/dev/null 2>&1; then pacman -Sy --noconfirm docker;",
+		"else echo 'no supported package manager found' >&2; exit 1; fi",
+	}, " "))
+}
+
+// InstallDockerScript installs Docker with the official convenience script from get.docker.com.
+func InstallDockerScript() string {
+	return AsRoot("curl -fsSL https://get.docker.com | sh")
+}
+
+// EnableDocker starts Docker daemon and enables it on boot.
+func EnableDocker() string {
+	return AsRoot("systemctl enable --now docker || { rc-update add docker default && rc-service docker start; } || service docker start")
+}
+
+// AddUserToDockerGroup allows user to run docker without sudo.
+func AddUserToDockerGroup(user string) string {
+	return AsRoot(fmt.Sprintf("usermod -aG docker %s", shellescape.Quote(user)))
+}
+
+// InstallPodmanPackage installs Podman with the package manager found on the host.
+func InstallPodmanPackage() string {
+	return AsRoot(strings.Join([]string{
+		"if command -v apt-get >/dev/null 2>&1; then apt-get update && apt-get install -y podman;",
+		"elif command -v dnf >/dev/null 2>&1; then dnf install -y podman;",
+		"elif command -v yum >/dev/null 2>&1; then yum install -y podman;",
+		"elif command -v zypper >/dev/null 2>&1; then zypper --non-interactive install podman;",
+		"elif command -v apk >/dev/null 2>&1; then apk add podman;",
+		"elif command -v pacman >/dev/null 2>&1; then pacman -Sy --noconfirm podman;",
+		"else echo 'no supported package manager found' >&2; exit 1; fi",
+	}, " "))
+}
+
+// EnablePodman starts the API socket used by the proxy and the service that starts
+// containers on boot. Rootless users get user services that keep running after logout.
+func EnablePodman() string {
+	return `if [ "$(id -u)" -eq 0 ]; then systemctl enable --now podman.socket podman-restart.service; ` +
+		`else systemctl --user enable --now podman.socket podman-restart.service && loginctl enable-linger "$(id -un)"; fi`
+}
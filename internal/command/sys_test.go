@@ -0,0 +1,19 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsRoot(t *testing.T) {
+	got := AsRoot("usermod -aG docker 'deploy'")
+	expected := `if [ "$(id -u)" -eq 0 ]; then sh -c 'usermod -aG docker '"'"'deploy'"'"''; else sudo sh -c 'usermod -aG docker '"'"'deploy'"'"''; fi`
+	assert.Equal(t, expected, got)
+}
+
+func TestAppendToFile(t *testing.T) {
+	got := AppendToFile("~/.faino/audit.log", `{"error":"it's\nbroken"}`)
+	expected := `umask 077 && printf '%s\n' '{"error":"it'"'"'s\nbroken"}' >> ~/.faino/audit.log && chmod 600 ~/.faino/audit.log`
+	assert.Equal(t, expected, got)
+}
@@ -8,10 +8,14 @@ import (
 )
 
 // Docker is a helper function for building long commands that may have conditional args.
-// For simple commands use fmt.
+// For simple commands use fmt. Commands run on servers are built with Container.
 func Docker(args ...string) string {
+	return join("docker", args)
+}
+
+func join(bin string, args []string) string {
 	var sb strings.Builder
-	sb.WriteString("docker")
+	sb.WriteString(bin)
 	for _, arg := range args {
 		if arg == "" {
 			continue
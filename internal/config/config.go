@@ -4,15 +4,22 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"net/url"
 	"os"
+	"regexp"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/posflag"
 	"github.com/knadh/koanf/v2"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/notify"
 	"github.com/lex-unix/faino/internal/validator"
 	"github.com/spf13/pflag"
 )
@@ -25,19 +32,37 @@ const (
 	builder = "faino-hybrid"
 
 	// config defaults
-	defaultDriver         = "docker-container"
-	defaultDockerfilePath = "."
-	defaultSSHPort        = 22
-	defaultSSHUser        = "root"
-	defaultProxyContainer = "traefik"
-	defaultProxyImage     = "traefik:v3.1"
-	defaultRegistryServer = "docker.io"
+	defaultDriver          = "docker-container"
+	defaultDockerfilePath  = "."
+	defaultSSHPort         = 22
+	defaultSSHUser         = "root"
+	defaultProxyContainer  = "traefik"
+	defaultProxyImage      = "traefik:v3.1"
+	defaultRegistryServer  = "docker.io"
+	defaultMinDiskSpaceMB  = 1024
+	defaultNotifyTimeout   = 5 * time.Second
+	defaultHealthPort      = 80
+	defaultHealthInterval  = time.Second
+	defaultHealthTimeout   = 30 * time.Second
+	defaultDeployTimeout   = 30 * time.Minute
+	defaultStepTimeout     = 5 * time.Minute
+	defaultPullTimeout     = 15 * time.Minute
+	defaultRollbackTimeout = 5 * time.Minute
+	defaultDeployQuorum    = 100
+	defaultRuntime         = "docker"
+	defaultEngine          = "cli"
 )
 
 var (
 	ErrNotExists = errors.New("config does not exist")
 )
 
+var (
+	cronJobNameRX  = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	cronScheduleRX = regexp.MustCompile(`^(@(yearly|annually|monthly|weekly|daily|midnight|hourly)|(\S+\s+){4}\S+)$`)
+	healthPathRX   = regexp.MustCompile(`^/[^\s"$\x60\\]*$`)
+)
+
 type Proxy struct {
 	Container string         `koanf:"container"`
 	Img       string         `koanf:"image"`
@@ -47,8 +72,16 @@ type Proxy struct {
 }
 
 type SSH struct {
-	User string `koanf:"user"`
-	Port int64  `koanf:"port"`
+	User            string `koanf:"user"`
+	Port            int64  `koanf:"port"`
+	Retry           Retry  `koanf:"retry"`
+	TrustOnFirstUse bool   `koanf:"trust_on_first_use"`
+}
+
+type Retry struct {
+	Attempts   int           `koanf:"attempts"`
+	Backoff    time.Duration `koanf:"backoff"`
+	MaxBackoff time.Duration `koanf:"max_backoff"`
 }
 
 type Registry struct {
@@ -61,6 +94,89 @@ type Transaction struct {
 	Bypass bool `koanf:"bypass"`
 }
 
+type Hook struct {
+	Command string `koanf:"command"`
+	// Remote runs the command in a one-off container of the deployed image instead of locally
+	Remote bool `koanf:"remote"`
+	// Once runs remote hook on a single server, whichever reaches it first
+	Once bool `koanf:"once"`
+}
+
+type Hooks struct {
+	PreBuild     []Hook `koanf:"pre-build"`
+	PreDeploy    []Hook `koanf:"pre-deploy"`
+	PostDeploy   []Hook `koanf:"post-deploy"`
+	PostRollback []Hook `koanf:"post-rollback"`
+}
+
+type CronJob struct {
+	Name     string `koanf:"name"`
+	Schedule string `koanf:"schedule"`
+	Command  string `koanf:"command"`
+}
+
+type Cron struct {
+	// Hosts where jobs are installed, defaults to the first server
+	Hosts []string  `koanf:"hosts"`
+	Jobs  []CronJob `koanf:"jobs"`
+}
+
+type Webhook struct {
+	URL string `koanf:"url"`
+	// Events the webhook receives, all events if empty
+	Events []string `koanf:"events"`
+}
+
+type Notifications struct {
+	Webhooks []Webhook     `koanf:"webhooks"`
+	Timeout  time.Duration `koanf:"timeout"`
+}
+
+// HealthCheck tells when a started container is ready to serve traffic. Either Path
+// is requested over HTTP on Port, or Command is run inside the container.
+type HealthCheck struct {
+	Path     string        `koanf:"path"`
+	Port     int           `koanf:"port"`
+	Command  string        `koanf:"command"`
+	Interval time.Duration `koanf:"interval"`
+	Timeout  time.Duration `koanf:"timeout"`
+	// Drain is how long both containers serve traffic before the old one is stopped
+	Drain time.Duration `koanf:"drain"`
+}
+
+// Enabled reports whether a check is configured.
+func (h HealthCheck) Enabled() bool {
+	return h.Path != "" || h.Command != ""
+}
+
+// Deploy limits how long deploy may run, zero disables a limit.
+type Deploy struct {
+	// Timeout is the deadline of the whole deploy, including build
+	Timeout time.Duration `koanf:"timeout"`
+	// StepTimeout limits every step on servers that has no timeout of its own
+	StepTimeout time.Duration `koanf:"step_timeout"`
+	PullTimeout time.Duration `koanf:"pull_timeout"`
+	// Quorum is the percentage of servers the deploy has to succeed on. Servers
+	// that failed are rolled back on their own if the quorum is met.
+	Quorum int `koanf:"quorum"`
+}
+
+type Rollback struct {
+	// Timeout is the deadline of `faino rollback` and of undoing a failed deploy
+	Timeout time.Duration `koanf:"timeout"`
+}
+
+type Audit struct {
+	// Local is path of audit log kept on the local machine in addition to servers, disabled if empty
+	Local string `koanf:"local"`
+}
+
+type Preflight struct {
+	Skip bool `koanf:"skip"`
+	// MinDiskSpace is free space in megabytes required on hosts, 0 disables the check
+	MinDiskSpace int64 `koanf:"min_disk_space_mb"`
+}
+
 type Build struct {
 	Dockerfile string            `koanf:"dockerfile"`
 	Args       map[string]string `koanf:"args"`
@@ -70,33 +186,72 @@ type Build struct {
 	Builder    string
 }
 
+type File struct {
+	Local  string `koanf:"local"`
+	Remote string `koanf:"remote"`
+	// Mode is an octal permission string like "0644". Local file mode is used if empty.
+	Mode string `koanf:"mode"`
+}
+
 type Config struct {
 	AppName     string
-	Service     string            `koanf:"service"`
-	Image       string            `koanf:"image"`
-	Transaction Transaction       `koanf:"transaction"`
-	Servers     []string          `koanf:"servers"`
-	Host        string            `koanf:"host"`
-	SSH         SSH               `koanf:"ssh"`
-	Registry    Registry          `koanf:"registry"`
-	Proxy       Proxy             `koanf:"proxy"`
-	Build       Build             `koanf:"build"`
-	Debug       bool              `koanf:"debug"`
-	Env         map[string]string `koanf:"env"`
-	Volumes     []string          `koanf:"volumes"`
+	Service     string      `koanf:"service"`
+	Image       string      `koanf:"image"`
+	Transaction Transaction `koanf:"transaction"`
+	Servers     []string    `koanf:"servers"`
+	Host        string      `koanf:"host"`
+	// Runtime runs containers on servers, either docker or podman
+	Runtime string `koanf:"runtime"`
+	// Engine is how containers are inspected on servers, either cli or api,
+	// which talks to the Docker Engine API over SSH
+	Engine         string            `koanf:"engine"`
+	AcceptHostKeys bool              `koanf:"accept-host-keys"`
+	SSH            SSH               `koanf:"ssh"`
+	Registry       Registry          `koanf:"registry"`
+	Proxy          Proxy             `koanf:"proxy"`
+	Build          Build             `koanf:"build"`
+	Debug          bool              `koanf:"debug"`
+	Env            map[string]string `koanf:"env"`
+	Volumes        []string          `koanf:"volumes"`
+	Files          []File            `koanf:"files"`
+	Preflight      Preflight         `koanf:"preflight"`
+	Hooks          Hooks             `koanf:"hooks"`
+	Cron           Cron              `koanf:"cron"`
+	Notifications  Notifications     `koanf:"notifications"`
+	Audit          Audit             `koanf:"audit"`
+	HealthCheck    HealthCheck       `koanf:"healthcheck"`
+	Deploy         Deploy            `koanf:"deploy"`
+	Rollback       Rollback          `koanf:"rollback"`
 }
 
 var k = koanf.New(".")
 
 func Load(f *pflag.FlagSet) (*Config, error) {
 	k.Set("transaction.bypass", false)
+	k.Set("runtime", defaultRuntime)
+	k.Set("engine", defaultEngine)
 	k.Set("ssh.port", defaultSSHPort)
 	k.Set("ssh.user", defaultSSHUser)
+	k.Set("ssh.retry.attempts", sshexec.DefaultRetryPolicy.Attempts)
+	k.Set("ssh.retry.backoff", sshexec.DefaultRetryPolicy.Backoff)
+	k.Set("ssh.retry.max_backoff", sshexec.DefaultRetryPolicy.MaxBackoff)
+	k.Set("ssh.trust_on_first_use", false)
 	k.Set("proxy.container", defaultProxyContainer)
 	k.Set("proxy.image", defaultProxyImage)
 	k.Set("build.dockerfile", defaultDockerfilePath)
 	k.Set("build.driver", defaultDriver)
 	k.Set("registry.server", defaultRegistryServer)
+	k.Set("preflight.skip", false)
+	k.Set("preflight.min_disk_space_mb", defaultMinDiskSpaceMB)
+	k.Set("notifications.timeout", defaultNotifyTimeout)
+	k.Set("healthcheck.port", defaultHealthPort)
+	k.Set("healthcheck.interval", defaultHealthInterval)
+	k.Set("healthcheck.timeout", defaultHealthTimeout)
+	k.Set("deploy.timeout", defaultDeployTimeout)
+	k.Set("deploy.step_timeout", defaultStepTimeout)
+	k.Set("deploy.pull_timeout", defaultPullTimeout)
+	k.Set("deploy.quorum", defaultDeployQuorum)
+	k.Set("rollback.timeout", defaultRollbackTimeout)
 	k.Set("debug", false)
 
 	configFile := fmt.Sprintf("%s.yaml", appName)
@@ -144,6 +299,9 @@ func Load(f *pflag.FlagSet) (*Config, error) {
 	cfg.Build.Secrets = expandMapEnv(cfg.Build.Secrets)
 	cfg.Env = expandMapEnv(cfg.Env)
 	cfg.Build.Args = expandMapEnv(cfg.Build.Args)
+	for i := range cfg.Notifications.Webhooks {
+		cfg.Notifications.Webhooks[i].URL = expandEnv(cfg.Notifications.Webhooks[i].URL)
+	}
 
 	return cfg, nil
 }
@@ -159,10 +317,78 @@ func validate(cfg *Config) error {
 	v.Check(len(cfg.Servers) > 0, "servers", "must provide at leat 1 remote server")
 	v.Check(cfg.Registry.Username != "", "registry.username", "must provide registry username")
 	v.Check(cfg.Registry.Password != "", "registry.password", "must provide registry password")
+	// empty runtime and engine are docker and cli
+	v.Check(validator.In(cfg.Runtime, "", "docker", "podman"), "runtime", "valid runtime is either docker or podman")
+	v.Check(validator.In(cfg.Engine, "", "cli", "api"), "engine", "valid engine is either cli or api")
+	if cfg.Engine == "api" {
+		v.Check(cfg.Runtime != "podman", "engine", "api engine is only supported with docker runtime")
+	}
 	v.Check(validator.In(cfg.Build.Driver, "docker", "docker-container"), "build.driver", "valid driver is either docker or docker-container")
 	if cfg.Build.Driver == "docker" {
 		v.Check(len(cfg.Build.Arch) <= 1, "build.arch", "docker driver only supports single architecture builds, use docker-container driver for multi-arch")
 	}
+	v.Check(cfg.SSH.Retry.Attempts >= 0, "ssh.retry.attempts", "must not be negative")
+	v.Check(cfg.SSH.Retry.Backoff >= 0, "ssh.retry.backoff", "must not be negative")
+	for i, f := range cfg.Files {
+		key := fmt.Sprintf("files[%d]", i)
+		v.Check(f.Local != "", key+".local", "must provide local path")
+		v.Check(f.Remote != "", key+".remote", "must provide remote path")
+		if f.Mode != "" {
+			_, err := ParseFileMode(f.Mode)
+			v.Check(err == nil, key+".mode", fmt.Sprintf("mode %s is invalid, must be octal like 0644", f.Mode))
+		}
+	}
+	hooks := map[string][]Hook{
+		"pre-build":     cfg.Hooks.PreBuild,
+		"pre-deploy":    cfg.Hooks.PreDeploy,
+		"post-deploy":   cfg.Hooks.PostDeploy,
+		"post-rollback": cfg.Hooks.PostRollback,
+	}
+	for name, list := range hooks {
+		for i, h := range list {
+			key := fmt.Sprintf("hooks.%s[%d]", name, i)
+			v.Check(h.Command != "", key+".command", "must provide command")
+			v.Check(!h.Once || h.Remote, key+".once", "only remote hooks can run once")
+		}
+	}
+	for i, h := range cfg.Hooks.PreBuild {
+		v.Check(!h.Remote, fmt.Sprintf("hooks.pre-build[%d].remote", i), "pre-build hooks run before image exists and cannot be remote")
+	}
+	cronJobNames := make([]string, 0, len(cfg.Cron.Jobs))
+	for i, job := range cfg.Cron.Jobs {
+		key := fmt.Sprintf("cron.jobs[%d]", i)
+		v.Check(validator.Matches(job.Name, cronJobNameRX), key+".name", "must contain only letters, digits, dashes and underscores")
+		v.Check(validator.Matches(job.Schedule, cronScheduleRX), key+".schedule", fmt.Sprintf("schedule %q is invalid, must have 5 fields or be a macro like @daily", job.Schedule))
+		v.Check(job.Command != "", key+".command", "must provide command")
+		cronJobNames = append(cronJobNames, job.Name)
+	}
+	v.Check(validator.Unique(cronJobNames), "cron.jobs", "job names must be unique")
+	for _, host := range cfg.Cron.Hosts {
+		v.Check(validator.In(host, cfg.Servers...), "cron.hosts", fmt.Sprintf("host %s was not found in 'servers' array", host))
+	}
+	for i, hook := range cfg.Notifications.Webhooks {
+		key := fmt.Sprintf("notifications.webhooks[%d]", i)
+		u, err := url.Parse(expandEnv(hook.URL))
+		v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", key+".url", "must be a valid http or https URL")
+		for _, event := range hook.Events {
+			v.Check(slices.Contains(notify.Events, notify.Event(event)), key+".events", fmt.Sprintf("event %s is invalid, must be one of %s", event, webhookEventNames()))
+		}
+	}
+	if hc := cfg.HealthCheck; hc.Enabled() {
+		v.Check(hc.Path == "" || hc.Command == "", "healthcheck", "either path or command can be set, not both")
+		if hc.Path != "" {
+			v.Check(validator.Matches(hc.Path, healthPathRX), "healthcheck.path", "must start with / and contain no spaces, quotes or $")
+			v.Check(hc.Port > 0 && hc.Port <= 65535, "healthcheck.port", "must be a valid port")
+		}
+		v.Check(hc.Interval > 0, "healthcheck.interval", "must be positive")
+		v.Check(hc.Timeout > 0, "healthcheck.timeout", "must be positive")
+	}
+	v.Check(cfg.HealthCheck.Drain >= 0, "healthcheck.drain", "must not be negative")
+	v.Check(cfg.Deploy.Timeout >= 0, "deploy.timeout", "must not be negative")
+	v.Check(cfg.Deploy.StepTimeout >= 0, "deploy.step_timeout", "must not be negative")
+	v.Check(cfg.Deploy.PullTimeout >= 0, "deploy.pull_timeout", "must not be negative")
+	v.Check(cfg.Deploy.Quorum >= 0 && cfg.Deploy.Quorum <= 100, "deploy.quorum", "must be a percentage between 0 and 100")
+	v.Check(cfg.Rollback.Timeout >= 0, "rollback.timeout", "must not be negative")
 	for _, arch := range cfg.Build.Arch {
 		v.Check(validator.In(arch, "arm64", "amd64"), "build.arch", fmt.Sprintf("arch %s is invalid, must be either amd64 or arm64", arch))
 	}
@@ -179,6 +405,10 @@ func setDefaults(cfg *Config) {
 		cfg.Image = cfg.Service
 	}
 
+	if len(cfg.Cron.Jobs) > 0 && len(cfg.Cron.Hosts) == 0 && len(cfg.Servers) > 0 {
+		cfg.Cron.Hosts = []string{cfg.Servers[0]}
+	}
+
 	if len(cfg.Build.Arch) == 0 {
 		switch cfg.Build.Driver {
 		case "docker":
@@ -189,10 +419,36 @@ func setDefaults(cfg *Config) {
 	}
 }
 
+// ParseFileMode parses octal permission string like "0644".
+func ParseFileMode(mode string) (os.FileMode, error) {
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	if m > 0777 {
+		return 0, fmt.Errorf("mode %s is out of range", mode)
+	}
+	return os.FileMode(m), nil
+}
+
+// webhookEventNames lists events webhooks can subscribe to, separated by commas.
+func webhookEventNames() string {
+	names := make([]string, len(notify.Events))
+	for i, event := range notify.Events {
+		names[i] = string(event)
+	}
+	return strings.Join(names, ", ")
+}
+
 func Get() *Config {
 	return cfg
 }
 
+// Set replaces the loaded config, e.g. in tests of packages that read it with Get.
+func Set(c *Config) {
+	cfg = c
+}
+
 func expandEnv(orig string) string {
 	if expanded := os.ExpandEnv(orig); expanded != "" {
 		return expanded
@@ -88,6 +88,154 @@ func TestValidate(t *testing.T) {
 			},
 			invalidFields: []string{"build.driver"},
 		},
+		{
+			name:     "invalid file mode",
+			wantsErr: true,
+			config: &Config{
+				Service: "config-test",
+				Servers: []string{"test1.com"},
+				Registry: Registry{
+					Username: "test-user",
+					Password: "test-password",
+				},
+				Build: Build{
+					Driver: "docker",
+					Arch:   []string{"amd64"},
+				},
+				Files: []File{
+					{Local: "nginx.conf", Remote: "/etc/nginx.conf", Mode: "0999"},
+					{Local: "", Remote: ""},
+				},
+			},
+			invalidFields: []string{"files[0].mode", "files[1].local", "files[1].remote"},
+		},
+		{
+			name:     "invalid hooks",
+			wantsErr: true,
+			config: &Config{
+				Service: "config-test",
+				Servers: []string{"test1.com"},
+				Registry: Registry{
+					Username: "test-user",
+					Password: "test-password",
+				},
+				Build: Build{
+					Driver: "docker",
+					Arch:   []string{"amd64"},
+				},
+				Hooks: Hooks{
+					PreBuild:   []Hook{{Command: "make assets", Remote: true}},
+					PreDeploy:  []Hook{{Command: ""}},
+					PostDeploy: []Hook{{Command: "notify", Once: true}},
+				},
+			},
+			invalidFields: []string{"hooks.pre-build[0].remote", "hooks.pre-deploy[0].command", "hooks.post-deploy[0].once"},
+		},
+		{
+			name:     "invalid cron jobs",
+			wantsErr: true,
+			config: &Config{
+				Service: "config-test",
+				Servers: []string{"test1.com"},
+				Registry: Registry{
+					Username: "test-user",
+					Password: "test-password",
+				},
+				Build: Build{
+					Driver: "docker",
+					Arch:   []string{"amd64"},
+				},
+				Cron: Cron{
+					Hosts: []string{"test2.com"},
+					Jobs: []CronJob{
+						{Name: "clean up", Schedule: "* * *", Command: "cleanup"},
+						{Name: "report", Schedule: "@daily", Command: ""},
+					},
+				},
+			},
+			invalidFields: []string{"cron.hosts", "cron.jobs[0].name", "cron.jobs[0].schedule", "cron.jobs[1].command"},
+		},
+		{
+			name:     "invalid health check",
+			wantsErr: true,
+			config: &Config{
+				Service: "config-test",
+				Servers: []string{"test1.com"},
+				Registry: Registry{
+					Username: "test-user",
+					Password: "test-password",
+				},
+				Build: Build{Driver: "docker-container"},
+				HealthCheck: HealthCheck{
+					Path:    "/up $(reboot)",
+					Command: "true",
+				},
+			},
+			invalidFields: []string{"healthcheck", "healthcheck.path", "healthcheck.port", "healthcheck.interval", "healthcheck.timeout"},
+		},
+		{
+			name:     "invalid deploy quorum",
+			wantsErr: true,
+			config: &Config{
+				Service: "config-test",
+				Servers: []string{"test1.com"},
+				Registry: Registry{
+					Username: "test-user",
+					Password: "test-password",
+				},
+				Build:  Build{Driver: "docker-container"},
+				Deploy: Deploy{Quorum: 120},
+			},
+			invalidFields: []string{"deploy.quorum"},
+		},
+		{
+			name:     "unknown webhook event",
+			wantsErr: true,
+			config: &Config{
+				Service: "config-test",
+				Servers: []string{"test1.com"},
+				Registry: Registry{
+					Username: "test-user",
+					Password: "test-password",
+				},
+				Build: Build{Driver: "docker-container"},
+				Notifications: Notifications{Webhooks: []Webhook{
+					{URL: "https://hooks.example.com", Events: []string{"deploy.success", "deploy.done"}},
+				}},
+			},
+			invalidFields: []string{"notifications.webhooks[0].events"},
+		},
+		{
+			name:     "invalid runtime",
+			wantsErr: true,
+			config: &Config{
+				Service: "config-test",
+				Servers: []string{"test1.com"},
+				Runtime: "containerd",
+				Registry: Registry{
+					Username: "test-user",
+					Password: "test-password",
+				},
+				Build: Build{Driver: "docker-container"},
+			},
+			invalidFields: []string{"runtime"},
+		},
+		{
+			name:     "api engine with podman",
+			wantsErr: true,
+			config: &Config{
+				Service: "config-test",
+				Servers: []string{"test1.com"},
+				Runtime: "podman",
+				Engine:  "api",
+				Registry: Registry{
+					Username: "test-user",
+					Password: "test-password",
+				},
+				Build: Build{Driver: "docker-container"},
+			},
+			invalidFields: []string{"engine"},
+		},
 		{
 			name:     "multi-arch with docker driver",
 			wantsErr: true,
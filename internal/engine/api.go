@@ -0,0 +1,256 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/lex-unix/faino/internal/logging"
+	"golang.org/x/crypto/ssh"
+)
+
+// API implements Engine with the Docker Engine API, reached through the socket
+// on the host. If the socket can't be dialed, e.g. because the SSH server does not
+// allow forwarding it, API warns once and runs every call with fallback instead.
+// A dial that failed because of the connection itself only falls back for that
+// call, the socket is tried again on the next one.
+type API struct {
+	host     string
+	http     *http.Client
+	fallback Engine
+	// unreachable is set once the socket was refused on a working connection
+	unreachable atomic.Bool
+}
+
+func NewAPI(host string, dialer Dialer, fallback Engine) *API {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			conn, err := dialer.DialUnix(ctx, DockerSocket)
+			if err != nil {
+				return nil, &dialError{err: err}
+			}
+			return conn, nil
+		},
+		// the SSH channel is cheap to reopen, idle ones would only hold the connection
+		DisableKeepAlives: true,
+	}
+	return &API{
+		host:     host,
+		http:     &http.Client{Transport: transport},
+		fallback: fallback,
+	}
+}
+
+func (a *API) ContainerInspect(ctx context.Context, name string) (Container, error) {
+	if a.unreachable.Load() {
+		return a.fallback.ContainerInspect(ctx, name)
+	}
+	var v containerJSON
+	err := a.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", nil, &v)
+	if a.fallBack(err) {
+		return a.fallback.ContainerInspect(ctx, name)
+	}
+	return v.container(), err
+}
+
+func (a *API) ContainerStart(ctx context.Context, name string) error {
+	if a.unreachable.Load() {
+		return a.fallback.ContainerStart(ctx, name)
+	}
+	err := a.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/start", nil, nil)
+	if a.fallBack(err) {
+		return a.fallback.ContainerStart(ctx, name)
+	}
+	return err
+}
+
+func (a *API) ImageInspect(ctx context.Context, ref string) (Image, error) {
+	if a.unreachable.Load() {
+		return a.fallback.ImageInspect(ctx, ref)
+	}
+	var v imageJSON
+	err := a.do(ctx, http.MethodGet, "/images/"+url.PathEscape(ref)+"/json", nil, &v)
+	if a.fallBack(err) {
+		return a.fallback.ImageInspect(ctx, ref)
+	}
+	return v.image(), err
+}
+
+func (a *API) NetworkCreate(ctx context.Context, name string) (Network, error) {
+	if a.unreachable.Load() {
+		return a.fallback.NetworkCreate(ctx, name)
+	}
+	req := map[string]any{"Name": name, "Driver": "bridge", "CheckDuplicate": true}
+	var v networkJSON
+	err := a.do(ctx, http.MethodPost, "/networks/create", req, &v)
+	if a.fallBack(err) {
+		return a.fallback.NetworkCreate(ctx, name)
+	}
+	if err != nil {
+		return Network{}, err
+	}
+	return Network{ID: v.ID, Name: name, Driver: "bridge"}, nil
+}
+
+func (a *API) NetworkInspect(ctx context.Context, name string) (Network, error) {
+	if a.unreachable.Load() {
+		return a.fallback.NetworkInspect(ctx, name)
+	}
+	var v networkJSON
+	err := a.do(ctx, http.MethodGet, "/networks/"+url.PathEscape(name), nil, &v)
+	if a.fallBack(err) {
+		return a.fallback.NetworkInspect(ctx, name)
+	}
+	return v.network(), err
+}
+
+// fallBack reports whether the call failed because the socket is unreachable
+// and has to be retried with the fallback engine.
+func (a *API) fallBack(err error) bool {
+	var dialErr *dialError
+	if !errors.As(err, &dialErr) {
+		return false
+	}
+	if !dialErr.permanent() {
+		logging.DebugHostf(a.host, "failed to dial docker API, falling back to CLI: %s", dialErr.err)
+		return true
+	}
+	if a.unreachable.CompareAndSwap(false, true) {
+		logging.WarnHostf(a.host, "docker API is unreachable, falling back to CLI: %s", dialErr.err)
+	}
+	return true
+}
+
+// do sends request with body encoded as JSON and decodes response into v if it is not nil.
+func (a *API) do(ctx context.Context, method, path string, body any, v any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	// host is ignored by the dialer, but required by net/http
+	req, err := http.NewRequestWithContext(ctx, method, "http://docker"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	logging.DebugHostf(a.host, "docker API %s %s", method, path)
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 304 is returned if the container is already started
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode >= 300 {
+		return a.responseError(resp)
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func (a *API) responseError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := json.Unmarshal(data, &body); err != nil || body.Message == "" {
+		body.Message = strings.TrimSpace(string(data))
+	}
+
+	var kind error
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		kind = ErrNotFound
+	case http.StatusConflict:
+		kind = ErrConflict
+	}
+	return &Error{Host: a.host, StatusCode: resp.StatusCode, Message: body.Message, kind: kind}
+}
+
+type dialError struct {
+	err error
+}
+
+func (e *dialError) Error() string {
+	return fmt.Sprintf("dial %s: %s", DockerSocket, e.err)
+}
+
+func (e *dialError) Unwrap() error {
+	return e.err
+}
+
+// permanent reports whether the socket itself can't be reached: the SSH server
+// refused to forward it, or it is missing or not accessible. Other errors, e.g. a
+// dropped connection, may go away after the client reconnects.
+func (e *dialError) permanent() bool {
+	var channelErr *ssh.OpenChannelError
+	return errors.As(e.err, &channelErr) ||
+		errors.Is(e.err, fs.ErrNotExist) ||
+		errors.Is(e.err, fs.ErrPermission)
+}
+
+// containerJSON is a container returned by inspect. Podman omits the slash
+// docker prefixes names with.
+type containerJSON struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image string `json:"Image"`
+	} `json:"Config"`
+	State struct {
+		Status string `json:"Status"`
+	} `json:"State"`
+}
+
+func (c containerJSON) container() Container {
+	return Container{
+		ID:    c.ID,
+		Name:  strings.TrimPrefix(c.Name, "/"),
+		Image: c.Config.Image,
+		State: c.State.Status,
+	}
+}
+
+type imageJSON struct {
+	ID       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+}
+
+func (i imageJSON) image() Image {
+	return Image{ID: i.ID, Tags: i.RepoTags}
+}
+
+type networkJSON struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Driver string `json:"Driver"`
+}
+
+func (n networkJSON) network() Network {
+	return Network{ID: n.ID, Name: n.Name, Driver: n.Driver}
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socketDialer dials the test server regardless of the requested path.
+type socketDialer struct {
+	path string
+}
+
+func (d socketDialer) DialUnix(ctx context.Context, _ string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "unix", d.path)
+}
+
+// flakyDialer fails the first dial as if the connection dropped.
+type flakyDialer struct {
+	socketDialer
+	failed bool
+}
+
+func (d *flakyDialer) DialUnix(ctx context.Context, path string) (net.Conn, error) {
+	if !d.failed {
+		d.failed = true
+		return nil, io.EOF
+	}
+	return d.socketDialer.DialUnix(ctx, path)
+}
+
+// engineStub records calls that fell back to it.
+type engineStub struct {
+	Engine
+	calls []string
+}
+
+func (e *engineStub) ContainerInspect(ctx context.Context, name string) (Container, error) {
+	e.calls = append(e.calls, "inspect "+name)
+	return Container{Name: name, State: "exited"}, nil
+}
+
+func newTestAPI(t *testing.T, handler http.Handler) (*API, *engineStub) {
+	socket := serveSocket(t, handler)
+	fallback := &engineStub{}
+	return NewAPI(t.Name(), socketDialer{path: socket}, fallback), fallback
+}
+
+// serveSocket serves handler on a unix socket and returns its path.
+func serveSocket(t *testing.T, handler http.Handler) string {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return socket
+}
+
+func TestAPI(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/app-v1/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id":"abc","Name":"/app-v1","Config":{"Image":"app:v1"},"State":{"Status":"running"}}`))
+	})
+	mux.HandleFunc("GET /containers/missing/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such container: missing"}`))
+	})
+	mux.HandleFunc("POST /containers/traefik/start", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	})
+	mux.HandleFunc("POST /networks/create", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"message":"network with name faino already exists"}`))
+	})
+	api, fallback := newTestAPI(t, mux)
+	ctx := context.Background()
+
+	t.Run("inspects container", func(t *testing.T) {
+		c, err := api.ContainerInspect(ctx, "app-v1")
+
+		require.NoError(t, err)
+		assert.Equal(t, Container{ID: "abc", Name: "app-v1", Image: "app:v1", State: "running"}, c)
+		assert.True(t, c.Running())
+	})
+
+	t.Run("returns typed errors", func(t *testing.T) {
+		_, err := api.ContainerInspect(ctx, "missing")
+
+		assert.ErrorIs(t, err, ErrNotFound)
+		var engineErr *Error
+		if assert.ErrorAs(t, err, &engineErr) {
+			assert.Equal(t, http.StatusNotFound, engineErr.StatusCode)
+			assert.Equal(t, "No such container: missing", engineErr.Message)
+		}
+
+		_, err = api.NetworkCreate(ctx, "faino")
+		assert.ErrorIs(t, err, ErrConflict)
+	})
+
+	t.Run("starting running container is not an error", func(t *testing.T) {
+		assert.NoError(t, api.ContainerStart(ctx, "traefik"))
+	})
+
+	assert.Empty(t, fallback.calls)
+}
+
+func TestAPIFallback(t *testing.T) {
+	fallback := &engineStub{}
+	api := NewAPI(t.Name(), socketDialer{path: filepath.Join(t.TempDir(), "missing.sock")}, fallback)
+
+	c, err := api.ContainerInspect(context.Background(), "app-v1")
+	require.NoError(t, err)
+	assert.Equal(t, "exited", c.State)
+
+	_, err = api.ContainerInspect(context.Background(), "app-v2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"inspect app-v1", "inspect app-v2"}, fallback.calls)
+}
+
+func TestAPIRetriesAfterTransientDialError(t *testing.T) {
+	socket := serveSocket(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id":"abc","Name":"/app-v1","State":{"Status":"running"}}`))
+	}))
+	fallback := &engineStub{}
+	api := NewAPI(t.Name(), &flakyDialer{socketDialer: socketDialer{path: socket}}, fallback)
+
+	c, err := api.ContainerInspect(context.Background(), "app-v1")
+	require.NoError(t, err)
+	assert.Equal(t, "exited", c.State)
+
+	c, err = api.ContainerInspect(context.Background(), "app-v1")
+	require.NoError(t, err)
+	assert.Equal(t, "running", c.State)
+	assert.Equal(t, []string{"inspect app-v1"}, fallback.calls)
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+)
+
+// CLI implements Engine by running commands of the runtime and parsing
+// their output. Inspect commands print the same JSON as the API.
+type CLI struct {
+	client  sshexec.Service
+	runtime command.Runtime
+}
+
+func NewCLI(client sshexec.Service, runtime command.Runtime) *CLI {
+	return &CLI{client: client, runtime: runtime}
+}
+
+func (c *CLI) ContainerInspect(ctx context.Context, name string) (Container, error) {
+	v, err := inspect[containerJSON](ctx, c, c.runtime.InspectContainer(name))
+	return v.container(), err
+}
+
+func (c *CLI) ContainerStart(ctx context.Context, name string) error {
+	return c.run(ctx, c.runtime.StartContainer(name), nil)
+}
+
+func (c *CLI) ImageInspect(ctx context.Context, ref string) (Image, error) {
+	v, err := inspect[imageJSON](ctx, c, c.runtime.InspectImage(ref))
+	return v.image(), err
+}
+
+func (c *CLI) NetworkCreate(ctx context.Context, name string) (Network, error) {
+	var out bytes.Buffer
+	if err := c.run(ctx, c.runtime.Container("network create", name), &out); err != nil {
+		return Network{}, err
+	}
+	return Network{ID: strings.TrimSpace(out.String()), Name: name, Driver: "bridge"}, nil
+}
+
+func (c *CLI) NetworkInspect(ctx context.Context, name string) (Network, error) {
+	v, err := inspect[networkJSON](ctx, c, c.runtime.Container("network inspect", name))
+	return v.network(), err
+}
+
+// inspect runs an inspect command, which prints an array of matched objects,
+// and returns the first one.
+func inspect[T any](ctx context.Context, c *CLI, cmd string) (T, error) {
+	var zero T
+	var out bytes.Buffer
+	if err := c.run(ctx, cmd, &out, sshexec.WithRetry()); err != nil {
+		return zero, err
+	}
+	var objects []T
+	if err := json.Unmarshal(out.Bytes(), &objects); err != nil {
+		return zero, fmt.Errorf("failed to parse output of %q: %w", cmd, err)
+	}
+	if len(objects) == 0 {
+		return zero, &Error{Host: c.client.Host(), Message: "no such object", kind: ErrNotFound}
+	}
+	return objects[0], nil
+}
+
+// run runs cmd, writing stdout to out if it is not nil, and turns failures
+// reported by the runtime into Error.
+func (c *CLI) run(ctx context.Context, cmd string, out *bytes.Buffer, opts ...sshexec.SessionOption) error {
+	if out != nil {
+		opts = append(opts, sshexec.WithStdout(out))
+	}
+	err := c.client.Run(ctx, cmd, opts...)
+	var cmdErr *sshexec.CommandError
+	// a missing runtime is not a missing object, keep it as a command error
+	if !errors.As(err, &cmdErr) || cmdErr.NotFound() {
+		return err
+	}
+	return &Error{
+		Host:    c.client.Host(),
+		Message: cmdErr.Msg,
+		kind:    errorKind(cmdErr.Msg),
+		err:     err,
+	}
+}
+
+var (
+	// e.g. "No such container: app", "image not known", "network faino not found"
+	notFoundMessage = regexp.MustCompile(`no such (container|image|network|object)|(image|container) not known|network .*not found`)
+	conflictMessage = regexp.MustCompile(`already exists|already in use`)
+)
+
+// errorKind classifies messages of docker and podman, which have no error codes.
+func errorKind(msg string) error {
+	msg = strings.ToLower(msg)
+	switch {
+	case notFoundMessage.MatchString(msg):
+		return ErrNotFound
+	case conflictMessage.MatchString(msg):
+		return ErrConflict
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDocker puts a docker executable running script first in PATH.
+func fakeDocker(t *testing.T, script string) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker"), []byte("#!/bin/sh\n"+script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCLI(t *testing.T) {
+	local, err := sshexec.NewLocal()
+	require.NoError(t, err)
+	cli := NewCLI(local, command.RuntimeDocker)
+	ctx := context.Background()
+
+	t.Run("parses inspect output", func(t *testing.T) {
+		fakeDocker(t, `echo '[{"Id":"abc","Name":"/app-v1","Config":{"Image":"app:v1"},"State":{"Status":"running"}}]'`)
+
+		c, err := cli.ContainerInspect(ctx, "app-v1")
+
+		require.NoError(t, err)
+		assert.Equal(t, Container{ID: "abc", Name: "app-v1", Image: "app:v1", State: "running"}, c)
+	})
+
+	t.Run("classifies errors", func(t *testing.T) {
+		fakeDocker(t, `echo "Error response from daemon: No such image: app:v1" >&2; exit 1`)
+		_, err := cli.ImageInspect(ctx, "app:v1")
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		fakeDocker(t, `echo "Error response from daemon: network with name faino already exists" >&2; exit 1`)
+		_, err = cli.NetworkCreate(ctx, "faino")
+		assert.ErrorIs(t, err, ErrConflict)
+
+		var cmdErr *sshexec.CommandError
+		assert.ErrorAs(t, err, &cmdErr)
+
+		fakeDocker(t, `echo "Error: unable to find network with name or ID faino: network not found" >&2; exit 1`)
+		_, err = cli.NetworkInspect(ctx, "faino")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("missing runtime is not a missing object", func(t *testing.T) {
+		fakeDocker(t, `echo "sh: docker: command not found" >&2; exit 127`)
+
+		_, err := cli.ContainerInspect(ctx, "app-v1")
+
+		assert.NotErrorIs(t, err, ErrNotFound)
+		var cmdErr *sshexec.CommandError
+		if assert.ErrorAs(t, err, &cmdErr) {
+			assert.True(t, cmdErr.NotFound())
+		}
+	})
+
+	t.Run("returns network ID", func(t *testing.T) {
+		fakeDocker(t, `echo 1f2e3d`)
+
+		n, err := cli.NetworkCreate(ctx, "faino")
+
+		require.NoError(t, err)
+		assert.Equal(t, Network{ID: "1f2e3d", Name: "faino", Driver: "bridge"}, n)
+	})
+}
@@ -0,0 +1,130 @@
+// Package engine manages containers, images and networks on servers with typed
+// results and errors. It talks to the Docker Engine API over the SSH connection,
+// or falls back to running CLI commands built by the command package.
+package engine
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/lex-unix/faino/internal/command"
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+)
+
+// DockerSocket is the Docker Engine API socket on servers.
+const DockerSocket = "/var/run/docker.sock"
+
+var (
+	// ErrNotFound is returned if the container, image or network does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned if the object already exists or is in use.
+	ErrConflict = errors.New("conflict")
+)
+
+type Engine interface {
+	// ContainerInspect returns the container with name or ID.
+	ContainerInspect(ctx context.Context, name string) (Container, error)
+
+	// ContainerStart starts the container. Starting a running container is not an error.
+	ContainerStart(ctx context.Context, name string) error
+
+	// ImageInspect returns the image with reference or ID.
+	ImageInspect(ctx context.Context, ref string) (Image, error)
+
+	// NetworkCreate creates a bridge network. It returns ErrConflict if the network exists.
+	NetworkCreate(ctx context.Context, name string) (Network, error)
+
+	// NetworkInspect returns the network with name or ID.
+	NetworkInspect(ctx context.Context, name string) (Network, error)
+}
+
+type Container struct {
+	ID    string
+	Name  string
+	Image string
+	// State is one of created, running, paused, restarting, removing, exited or dead
+	State string
+}
+
+func (c Container) Running() bool {
+	return c.State == "running"
+}
+
+type Image struct {
+	ID   string
+	Tags []string
+}
+
+type Network struct {
+	ID     string
+	Name   string
+	Driver string
+}
+
+// Error is a failure reported by the runtime, either as an API response or as
+// output of a failed command.
+type Error struct {
+	Host string
+	// StatusCode is the HTTP status of the API response, 0 for CLI errors
+	StatusCode int
+	Message    string
+	kind       error
+	err        error
+}
+
+func (e *Error) Error() string {
+	return strings.TrimSpace(e.Message)
+}
+
+// Is reports whether the error is ErrNotFound or ErrConflict.
+func (e *Error) Is(target error) bool {
+	return e.kind != nil && target == e.kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Dialer connects to unix sockets on the host. It is implemented by sshexec.SSH
+// and sshexec.Local.
+type Dialer interface {
+	DialUnix(ctx context.Context, path string) (net.Conn, error)
+}
+
+type Option func(o *options)
+
+type options struct {
+	api     bool
+	runtime command.Runtime
+}
+
+// WithAPI uses the Docker Engine API if the client can reach the socket.
+func WithAPI() Option {
+	return func(o *options) {
+		o.api = true
+	}
+}
+
+// WithRuntime runs CLI commands of runtime, Docker by default.
+func WithRuntime(runtime command.Runtime) Option {
+	return func(o *options) {
+		o.runtime = runtime
+	}
+}
+
+// New returns the engine of the host client is connected to. Without WithAPI,
+// or if client can't dial sockets, commands are run with the CLI.
+func New(client sshexec.Service, opts ...Option) Engine {
+	o := options{runtime: command.RuntimeDocker}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cli := NewCLI(client, o.runtime)
+	if dialer, ok := client.(Dialer); ok && o.api {
+		return NewAPI(client.Host(), dialer, cli)
+	}
+	return cli
+}
@@ -25,7 +25,7 @@ type command struct {
 	pipeWg     sync.WaitGroup
 }
 
-func newCommand(client *SSH, cmd string) (*command, error) {
+func newCommand(client *SSH, conn *ssh.Client, cmd string) (*command, error) {
 	c := &command{}
 	c.client = client
 	c.cmd = cmd
@@ -34,7 +34,7 @@ func newCommand(client *SSH, cmd string) (*command, error) {
 	c.pipeErrors = make(chan error, 3) // stdout, stderr, stdin
 
 	var err error
-	c.session, err = client.conn.NewSession()
+	c.session, err = conn.NewSession()
 	if err != nil {
 		return nil, err
 	}
@@ -53,11 +53,18 @@ func (c *command) execute(ctx context.Context, opts sessionOptions) error {
 		runErr = c.run(opts)
 	}
 	close(c.done)
-	if runErr != nil && !<-c.killed {
-		return runErr
+	// the command may exit cleanly on SIGTERM, it still did not finish its work
+	if <-c.killed {
+		return &CommandError{
+			Host:    c.client.host,
+			Command: c.cmd,
+			Msg:     "terminated",
+			Code:    -1,
+			err:     ctx.Err(),
+		}
 	}
 
-	return nil
+	return runErr
 }
 
 func (c *command) run(opts sessionOptions) error {
@@ -1,6 +1,11 @@
 package sshexec
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
 
 type pipeError struct {
 	fd  fd
@@ -29,9 +34,17 @@ type CommandError struct {
 }
 
 func (e CommandError) Error() string {
+	if e.Terminated() {
+		return fmt.Sprintf("command %s was terminated: %s", e.Command, e.err)
+	}
 	return fmt.Sprintf("command %s failed with exit code %d", e.Command, e.Code)
 }
 
+// Terminated reports whether the command was stopped because its context was done.
+func (e CommandError) Terminated() bool {
+	return errors.Is(e.err, context.Canceled) || errors.Is(e.err, context.DeadlineExceeded)
+}
+
 func (e CommandError) Unwrap() error {
 	return e.err
 }
@@ -39,3 +52,36 @@ func (e CommandError) Unwrap() error {
 func (e CommandError) NotFound() bool {
 	return e.Code == 127
 }
+
+// RedactCommand returns message of err with the command of every CommandError
+// in its tree left out, since commands may carry secrets like env of the container.
+func RedactCommand(err error) string {
+	msg := err.Error()
+	for _, cmdErr := range commandErrors(err) {
+		if cmdErr.Command != "" {
+			msg = strings.ReplaceAll(msg, cmdErr.Command, fmt.Sprintf("<redacted> on %s", cmdErr.Host))
+		}
+	}
+	return msg
+}
+
+// commandErrors collects CommandErrors of err, including joined ones.
+func commandErrors(err error) []CommandError {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *CommandError:
+		return append([]CommandError{*e}, commandErrors(e.err)...)
+	case CommandError:
+		return append([]CommandError{e}, commandErrors(e.err)...)
+	case interface{ Unwrap() []error }:
+		var errs []CommandError
+		for _, err := range e.Unwrap() {
+			errs = append(errs, commandErrors(err)...)
+		}
+		return errs
+	case interface{ Unwrap() error }:
+		return commandErrors(e.Unwrap())
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package sshexec
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyConfirmFunc is called when a host is not present in known_hosts.
+// Returning true trusts the key and appends it to known_hosts.
+type HostKeyConfirmFunc func(host string, key ssh.PublicKey) (bool, error)
+
+// HostKeyStatus describes how a host key relates to known_hosts.
+type HostKeyStatus string
+
+const (
+	HostKeyKnown    HostKeyStatus = "known"
+	HostKeyUnknown  HostKeyStatus = "unknown"
+	HostKeyMismatch HostKeyStatus = "mismatch"
+)
+
+// HostKeyInfo is the result of scanning a host key.
+type HostKeyInfo struct {
+	Host        string
+	Type        string
+	Fingerprint string
+	Status      HostKeyStatus
+}
+
+var errHostKeyScanned = errors.New("host key scanned")
+
+// knownHostsMu serializes appends to known_hosts when several hosts are trusted at once.
+var knownHostsMu sync.Mutex
+
+func knownHostsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".ssh", "known_hosts"), nil
+}
+
+// ensureKnownHosts creates an empty known_hosts file if it does not exist yet.
+func ensureKnownHosts(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// tofuHostKeyCallback verifies keys against known_hosts and asks confirm
+// about hosts that are not there yet. A changed key is always rejected.
+// Accepted keys are verified on reconnect without asking again.
+func tofuHostKeyCallback(path string, confirm HostKeyConfirmFunc) (ssh.HostKeyCallback, error) {
+	if err := ensureKnownHosts(path); err != nil {
+		return nil, err
+	}
+	verify, err := knownhosts.New(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// mu guards verify, which is reloaded after a key was accepted
+	var mu sync.Mutex
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		err := verify(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if err == nil || !errors.As(err, &keyErr) || len(keyErr.Want) > 0 {
+			return err
+		}
+
+		ok, err := confirm(hostname, key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("host key for %s was not accepted", hostname)
+		}
+
+		if err := appendKnownHost(path, hostname, remote, key); err != nil {
+			return err
+		}
+		verify, err = knownhosts.New(path)
+		return err
+	}, nil
+}
+
+func appendKnownHost(path string, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	addrs := []string{knownhosts.Normalize(hostname)}
+	if remote != nil && knownhosts.Normalize(remote.String()) != addrs[0] {
+		addrs = append(addrs, knownhosts.Normalize(remote.String()))
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintln(f, knownhosts.Line(addrs, key))
+	return err
+}
+
+// ScanHostKey connects to the host only to read its public key
+// and reports whether it matches known_hosts. No authentication is attempted.
+func ScanHostKey(host string, port int64) (HostKeyInfo, error) {
+	path, err := knownHostsPath()
+	if err != nil {
+		return HostKeyInfo{}, err
+	}
+	if err := ensureKnownHosts(path); err != nil {
+		return HostKeyInfo{}, err
+	}
+	verify, err := knownhosts.New(path)
+	if err != nil {
+		return HostKeyInfo{}, err
+	}
+
+	info := HostKeyInfo{Host: host}
+	config := &ssh.ClientConfig{
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			info.Type = key.Type()
+			info.Fingerprint = ssh.FingerprintSHA256(key)
+			info.Status = HostKeyKnown
+			var keyErr *knownhosts.KeyError
+			if err := verify(hostname, remote, key); errors.As(err, &keyErr) {
+				if len(keyErr.Want) > 0 {
+					info.Status = HostKeyMismatch
+				} else {
+					info.Status = HostKeyUnknown
+				}
+			}
+			return errHostKeyScanned
+		},
+	}
+
+	conn, err := ssh.Dial("tcp", formatAddress(host, port), config)
+	if err == nil {
+		conn.Close()
+	}
+	if info.Fingerprint == "" {
+		return info, fmt.Errorf("failed to read host key of %s: %w", host, err)
+	}
+
+	return info, nil
+}
@@ -0,0 +1,99 @@
+package sshexec
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestPublicKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	return key
+}
+
+func TestTofuHostKeyCallback(t *testing.T) {
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 22}
+	hostname := "192.168.0.1:22"
+
+	t.Run("appends accepted key to known_hosts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		key := newTestPublicKey(t)
+		asked := 0
+
+		callback, err := tofuHostKeyCallback(path, func(host string, k ssh.PublicKey) (bool, error) {
+			asked++
+			return true, nil
+		})
+		require.NoError(t, err)
+		assert.NoError(t, callback(hostname, remote, key))
+		assert.Equal(t, 1, asked)
+
+		// key is now known, so a fresh callback must not ask again
+		callback, err = tofuHostKeyCallback(path, func(host string, k ssh.PublicKey) (bool, error) {
+			asked++
+			return false, nil
+		})
+		require.NoError(t, err)
+		assert.NoError(t, callback(hostname, remote, key))
+		assert.Equal(t, 1, asked)
+	})
+
+	t.Run("does not ask again on reconnect", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		key := newTestPublicKey(t)
+		asked := 0
+
+		callback, err := tofuHostKeyCallback(path, func(host string, k ssh.PublicKey) (bool, error) {
+			asked++
+			return true, nil
+		})
+		require.NoError(t, err)
+		require.NoError(t, callback(hostname, remote, key))
+		assert.NoError(t, callback(hostname, remote, key))
+		assert.Equal(t, 1, asked)
+		// a changed key is rejected by the same callback too
+		assert.Error(t, callback(hostname, remote, newTestPublicKey(t)))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Len(t, strings.Split(strings.TrimSpace(string(data)), "\n"), 1)
+	})
+
+	t.Run("rejects declined key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		callback, err := tofuHostKeyCallback(path, func(host string, k ssh.PublicKey) (bool, error) {
+			return false, nil
+		})
+		require.NoError(t, err)
+		assert.Error(t, callback(hostname, remote, newTestPublicKey(t)))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Empty(t, data)
+	})
+
+	t.Run("never trusts a changed key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		accept := func(host string, k ssh.PublicKey) (bool, error) { return true, nil }
+
+		callback, err := tofuHostKeyCallback(path, accept)
+		require.NoError(t, err)
+		require.NoError(t, callback(hostname, remote, newTestPublicKey(t)))
+
+		callback, err = tofuHostKeyCallback(path, accept)
+		require.NoError(t, err)
+		assert.Error(t, callback(hostname, remote, newTestPublicKey(t)))
+	})
+}
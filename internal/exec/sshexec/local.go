@@ -0,0 +1,159 @@
+package sshexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/lex-unix/faino/internal/logging"
+)
+
+// LocalHost is the server name that targets the machine faino runs on.
+// Commands run through os/exec instead of SSH.
+const LocalHost = "local"
+
+// Local implements Service on the local machine. Paths and commands are
+// relative to the home directory, like in an SSH session.
+type Local struct {
+	home string
+}
+
+func NewLocal() (*Local, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Local{home: home}, nil
+}
+
+func (l *Local) Host() string {
+	return LocalHost
+}
+
+// DialUnix connects to the unix socket at path.
+func (l *Local) DialUnix(ctx context.Context, path string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", path)
+}
+
+func (l *Local) Run(ctx context.Context, cmd string, options ...SessionOption) error {
+	opts := sessionOptions{
+		interactive: false,
+		stdout:      &logWriter{host: LocalHost},
+		stderr:      &logWriter{host: LocalHost},
+	}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	c := exec.CommandContext(ctx, "sh", "-c", cmd)
+	c.Dir = l.home
+	c.Stdin = opts.stdin
+	c.Stdout = opts.stdout
+
+	var stderrBuf bytes.Buffer
+	if opts.interactive {
+		c.Stderr = opts.stderr
+	} else {
+		c.Stderr = io.MultiWriter(&stderrBuf, opts.stderr)
+		logging.InfoHostf(LocalHost, "running command %q", cmd)
+	}
+
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return &CommandError{
+			Host:    LocalHost,
+			Command: cmd,
+			Msg:     stderrBuf.String(),
+			Code:    exitErr.ExitCode(),
+			err:     err,
+		}
+	}
+	return err
+}
+
+// path resolves name like SFTP does on a remote host.
+func (l *Local) path(name string) string {
+	name = remotePath(name)
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(l.home, name)
+}
+
+func (l *Local) ReadFile(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(l.path(name))
+	if err != nil {
+		return nil, &os.PathError{Op: "read", Path: name, Err: unwrapPathError(err)}
+	}
+	return data, nil
+}
+
+// WriteFile atomically replaces the file with data: it is written to a temporary
+// file in the same directory, which is then renamed over name.
+func (l *Local) WriteFile(_ context.Context, name string, data []byte, perm os.FileMode) error {
+	target := l.path(name)
+	tmp := filepath.Join(filepath.Dir(target), fmt.Sprintf(".%s.tmp-%d", filepath.Base(target), rand.Int63()))
+
+	err := func() error {
+		f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return err
+		}
+		// perm of OpenFile is subject to umask
+		if err := f.Chmod(perm); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmp, target)
+	}()
+	if err != nil {
+		_ = os.Remove(tmp)
+		return &os.PathError{Op: "write", Path: name, Err: unwrapPathError(err)}
+	}
+	return nil
+}
+
+func (l *Local) Stat(_ context.Context, name string) (os.FileInfo, error) {
+	return os.Stat(l.path(name))
+}
+
+func (l *Local) Remove(_ context.Context, name string) error {
+	return os.Remove(l.path(name))
+}
+
+func (l *Local) Rename(_ context.Context, oldname, newname string) error {
+	return os.Rename(l.path(oldname), l.path(newname))
+}
+
+func (l *Local) MkdirAll(_ context.Context, name string) error {
+	return os.MkdirAll(l.path(name), 0755)
+}
+
+// unwrapPathError returns the cause of err, so that it is not reported with the resolved path.
+func unwrapPathError(err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return pathErr.Err
+	}
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) {
+		return linkErr.Err
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package sshexec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocal(t *testing.T) {
+	home := t.TempDir()
+	l := &Local{home: home}
+
+	t.Run("runs commands in home directory", func(t *testing.T) {
+		var out bytes.Buffer
+		err := l.Run(context.Background(), "pwd && cat", WithStdout(&out), WithStdin(strings.NewReader("input")))
+
+		assert.NoError(t, err)
+		assert.Equal(t, home+"\ninput", out.String())
+	})
+
+	t.Run("reports exit code of failed command", func(t *testing.T) {
+		err := l.Run(context.Background(), "echo oops >&2; exit 3")
+
+		var cmdErr *CommandError
+		if assert.ErrorAs(t, err, &cmdErr) {
+			assert.Equal(t, 3, cmdErr.Code)
+			assert.Equal(t, "oops\n", cmdErr.Msg)
+			assert.Equal(t, LocalHost, cmdErr.Host)
+		}
+	})
+
+	t.Run("resolves files relative to home directory", func(t *testing.T) {
+		require.NoError(t, l.MkdirAll(context.Background(), "~/.faino"))
+		require.NoError(t, l.WriteFile(context.Background(), "~/.faino/history.json", []byte("[]"), 0600))
+
+		data, err := os.ReadFile(filepath.Join(home, ".faino", "history.json"))
+		assert.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+
+		info, err := l.Stat(context.Background(), "~/.faino/history.json")
+		if assert.NoError(t, err) {
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		}
+
+		require.NoError(t, l.Rename(context.Background(), "~/.faino/history.json", "~/.faino/old.json"))
+		data, err = l.ReadFile(context.Background(), "~/.faino/old.json")
+		assert.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+
+		require.NoError(t, l.Remove(context.Background(), "~/.faino/old.json"))
+		_, err = l.ReadFile(context.Background(), "~/.faino/old.json")
+		assert.True(t, errors.Is(err, os.ErrNotExist))
+	})
+}
@@ -0,0 +1,75 @@
+package sshexec
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// RetryPolicy describes how commands marked as idempotent are retried
+// after the connection to the host was dropped.
+type RetryPolicy struct {
+	// Attempts is the total number of attempts, including the first one.
+	Attempts int
+	// Backoff is the delay before the first retry. It doubles on every next retry.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is used when no policy was passed to New.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:   3,
+	Backoff:    time.Second,
+	MaxBackoff: 10 * time.Second,
+}
+
+// delay returns how long to wait before retry number attempt (starting at 1).
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// isConnectionError reports whether err was caused by a broken connection
+// rather than by the remote command itself.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return false
+	}
+
+	var exitMissing *ssh.ExitMissingError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &exitMissing):
+		return true
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, sftp.ErrSSHFxConnectionLost):
+		return true
+	case errors.Is(err, net.ErrClosed):
+		return true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.ECONNREFUSED):
+		return true
+	case errors.As(err, &netErr):
+		return true
+	}
+
+	return false
+}
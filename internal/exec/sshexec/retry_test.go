@@ -0,0 +1,60 @@
+package sshexec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{
+		Attempts:   5,
+		Backoff:    time.Second,
+		MaxBackoff: 5 * time.Second,
+	}
+
+	assert.Equal(t, time.Second, p.delay(1))
+	assert.Equal(t, 2*time.Second, p.delay(2))
+	assert.Equal(t, 4*time.Second, p.delay(3))
+	assert.Equal(t, 5*time.Second, p.delay(4))
+	assert.Equal(t, 5*time.Second, p.delay(10))
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "nil error",
+			err:      nil,
+			expected: false,
+		},
+		{
+			name:     "connection closed",
+			err:      fmt.Errorf("wait: %w", io.EOF),
+			expected: true,
+		},
+		{
+			name:     "command exited with non-zero code",
+			err:      &CommandError{Code: 1, err: io.EOF},
+			expected: false,
+		},
+		{
+			name:     "unrelated error",
+			err:      errors.New("something went wrong"),
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isConnectionError(tt.err))
+		})
+	}
+}
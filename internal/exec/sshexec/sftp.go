@@ -0,0 +1,189 @@
+package sshexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpClient returns SFTP client bound to the current connection,
+// opening a new one if the connection was replaced.
+func (s *SSH) sftpClient() (*sftp.Client, *ssh.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sftp != nil && s.sftpConn == s.conn {
+		return s.sftp, s.conn, nil
+	}
+	if s.sftp != nil {
+		_ = s.sftp.Close()
+		s.sftp = nil
+	}
+
+	client, err := sftp.NewClient(s.conn)
+	if err != nil {
+		return nil, s.conn, err
+	}
+	s.sftp = client
+	s.sftpConn = s.conn
+	return client, s.conn, nil
+}
+
+// withSFTP calls fn with SFTP client. If the connection drops, it reconnects
+// and, for idempotent operations, calls fn again according to the retry policy.
+// Nothing is started once ctx is done.
+func (s *SSH) withSFTP(ctx context.Context, idempotent bool, fn func(client *sftp.Client) error) error {
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		client, conn, err := s.sftpClient()
+		if err == nil {
+			err = fn(client)
+		}
+		if err == nil || !isConnectionError(err) {
+			return err
+		}
+
+		if reconnectErr := s.reconnect(conn); reconnectErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to reconnect: %w", reconnectErr))
+		}
+		if !idempotent || attempt >= s.retry.Attempts {
+			return err
+		}
+
+		delay := s.retry.delay(attempt)
+		logging.WarnHostf(s.host, "retrying file operation in %s: %s", delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// ReadFile reads the whole remote file.
+func (s *SSH) ReadFile(ctx context.Context, name string) ([]byte, error) {
+	var data []byte
+	err := s.withSFTP(ctx, true, func(client *sftp.Client) error {
+		f, err := client.Open(remotePath(name))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		data, err = io.ReadAll(f)
+		return err
+	})
+	if err != nil {
+		return nil, &os.PathError{Op: "read", Path: name, Err: err}
+	}
+	return data, nil
+}
+
+// WriteFile atomically replaces the remote file with data: it is written to
+// a temporary file in the same directory, which is then renamed over name.
+func (s *SSH) WriteFile(ctx context.Context, name string, data []byte, perm os.FileMode) error {
+	err := s.withSFTP(ctx, true, func(client *sftp.Client) error {
+		target := remotePath(name)
+		tmp := path.Join(path.Dir(target), fmt.Sprintf(".%s.tmp-%d", path.Base(target), rand.Int63()))
+
+		f, err := client.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			_ = client.Remove(tmp)
+			return err
+		}
+		if err := f.Chmod(perm); err != nil {
+			f.Close()
+			_ = client.Remove(tmp)
+			return err
+		}
+		if err := f.Close(); err != nil {
+			_ = client.Remove(tmp)
+			return err
+		}
+
+		if err := client.PosixRename(tmp, target); err != nil {
+			_ = client.Remove(tmp)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return &os.PathError{Op: "write", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Stat returns file info of the remote file.
+func (s *SSH) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	var info os.FileInfo
+	err := s.withSFTP(ctx, true, func(client *sftp.Client) error {
+		var err error
+		info, err = client.Stat(remotePath(name))
+		return err
+	})
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+// Remove removes the remote file or empty directory.
+func (s *SSH) Remove(ctx context.Context, name string) error {
+	err := s.withSFTP(ctx, false, func(client *sftp.Client) error {
+		return client.Remove(remotePath(name))
+	})
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Rename renames oldname to newname, replacing newname if it exists.
+func (s *SSH) Rename(ctx context.Context, oldname, newname string) error {
+	err := s.withSFTP(ctx, false, func(client *sftp.Client) error {
+		return client.PosixRename(remotePath(oldname), remotePath(newname))
+	})
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	return nil
+}
+
+// MkdirAll creates a remote directory along with any necessary parents.
+func (s *SSH) MkdirAll(ctx context.Context, name string) error {
+	err := s.withSFTP(ctx, true, func(client *sftp.Client) error {
+		return client.MkdirAll(remotePath(name))
+	})
+	if err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+// remotePath converts paths relative to home directory, like ~/.faino,
+// to paths SFTP understands. SFTP sessions start in the home directory,
+// so it is enough to strip the leading ~/.
+func remotePath(name string) string {
+	switch {
+	case name == "~":
+		return "."
+	case strings.HasPrefix(name, "~/"):
+		return name[2:]
+	default:
+		return name
+	}
+}
@@ -0,0 +1,37 @@
+package sshexec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemotePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "home directory",
+			input:    "~",
+			expected: ".",
+		},
+		{
+			name:     "relative to home directory",
+			input:    "~/.faino/history.json",
+			expected: ".faino/history.json",
+		},
+		{
+			name:     "absolute path",
+			input:    "/etc/faino/app env",
+			expected: "/etc/faino/app env",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, remotePath(tt.input))
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package sshexec
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -9,7 +8,11 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
+	"github.com/lex-unix/faino/internal/logging"
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/crypto/ssh/knownhosts"
@@ -34,25 +37,94 @@ var privateKeys = []string{
 type Service interface {
 	Run(ctx context.Context, cmd string, options ...SessionOption) error
 
-	ReadFile(path string) ([]byte, error)
+	// File operations stop retrying once ctx is done, but a transfer that has
+	// already started is not interrupted.
 
-	WriteFile(path string, data []byte) error
+	// ReadFile reads the whole remote file.
+	ReadFile(ctx context.Context, name string) ([]byte, error)
+
+	// WriteFile atomically writes data to the remote file with the given permissions.
+	// Readers see either the old or the new contents, never a partial write.
+	WriteFile(ctx context.Context, name string, data []byte, perm os.FileMode) error
+
+	// Stat returns file info of the remote file.
+	Stat(ctx context.Context, name string) (os.FileInfo, error)
+
+	// Remove removes the remote file or empty directory.
+	Remove(ctx context.Context, name string) error
+
+	// Rename renames oldname to newname, replacing newname if it exists.
+	Rename(ctx context.Context, oldname, newname string) error
+
+	// MkdirAll creates a remote directory along with any necessary parents.
+	MkdirAll(ctx context.Context, name string) error
 
 	Host() string
 }
 
+const (
+	keepaliveInterval = 15 * time.Second
+	keepaliveTimeout  = 10 * time.Second
+)
+
 type SSH struct {
-	conn *ssh.Client
-	host string
+	conn   *ssh.Client
+	host   string
+	addr   string
+	config *ssh.ClientConfig
+	retry  RetryPolicy
+
+	// confirmHostKey enables trust on first use for hosts missing from known_hosts
+	confirmHostKey HostKeyConfirmFunc
+
+	// sftp is lazily opened over sftpConn and reopened after reconnect
+	sftp     *sftp.Client
+	sftpConn *ssh.Client
+
+	// mu guards conn and sftp while reconnecting
+	mu sync.Mutex
+}
+
+type ClientOption func(s *SSH)
+
+// WithRetryPolicy sets the policy used to retry commands after the connection was dropped.
+func WithRetryPolicy(p RetryPolicy) ClientOption {
+	return func(s *SSH) {
+		s.retry = p
+	}
+}
+
+// WithHostKeyConfirm enables trust on first use: unknown hosts are passed to confirm
+// and, if accepted, added to known_hosts. Hosts whose key changed are still rejected.
+func WithHostKeyConfirm(confirm HostKeyConfirmFunc) ClientOption {
+	return func(s *SSH) {
+		s.confirmHostKey = confirm
+	}
 }
 
-func New(host, user string, port int64) (*SSH, error) {
+func New(host, user string, port int64, options ...ClientOption) (*SSH, error) {
+	s := &SSH{
+		host:  host,
+		addr:  formatAddress(host, port),
+		retry: DefaultRetryPolicy,
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, err
 	}
 	sshDir := filepath.Join(homeDir, ".ssh")
-	hostkeyCallback, err := knownhosts.New(filepath.Join(sshDir, "known_hosts"))
+	knownHostsFile := filepath.Join(sshDir, "known_hosts")
+
+	var hostkeyCallback ssh.HostKeyCallback
+	if s.confirmHostKey != nil {
+		hostkeyCallback, err = tofuHostKeyCallback(knownHostsFile, s.confirmHostKey)
+	} else {
+		hostkeyCallback, err = knownhosts.New(knownHostsFile)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -84,24 +156,99 @@ func New(host, user string, port int64) (*SSH, error) {
 		authMethod = ssh.PublicKeys(signers...)
 	}
 
-	config := &ssh.ClientConfig{
+	s.config = &ssh.ClientConfig{
 		User:            user,
 		Auth:            []ssh.AuthMethod{authMethod},
 		HostKeyCallback: hostkeyCallback,
 	}
 
-	client, err := ssh.Dial("tcp", formatAddress(host, port), config)
-	if err != nil {
+	if err := s.dial(); err != nil {
 		return nil, err
 	}
 
-	return &SSH{conn: client, host: host}, nil
+	return s, nil
+}
+
+// dial opens a new connection to the host and starts sending keepalives on it.
+// The caller must hold s.mu unless s is not shared yet.
+func (s *SSH) dial() error {
+	client, err := ssh.Dial("tcp", s.addr, s.config)
+	if err != nil {
+		return err
+	}
+	s.conn = client
+	go s.keepalive(client)
+	return nil
+}
+
+// client returns the current connection to the host.
+func (s *SSH) client() *ssh.Client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn
+}
+
+// reconnect replaces the broken connection with a new one.
+// If another command already replaced it, reconnect does nothing.
+func (s *SSH) reconnect(broken *ssh.Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != broken {
+		return nil
+	}
+	_ = broken.Close()
+	logging.WarnHost(s.host, "connection lost, reconnecting")
+	return s.dial()
+}
+
+// keepalive periodically pings the host and closes the connection if it stops responding,
+// so that pending sessions fail instead of hanging forever.
+func (s *SSH) keepalive(conn *ssh.Client) {
+	closed := make(chan struct{})
+	go func() {
+		_ = conn.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+			replied := make(chan error, 1)
+			go func() {
+				_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+				replied <- err
+			}()
+			select {
+			case err := <-replied:
+				if err == nil {
+					continue
+				}
+				logging.DebugHostf(s.host, "keepalive failed: %s", err)
+			case <-time.After(keepaliveTimeout):
+				logging.DebugHost(s.host, "keepalive timed out")
+			case <-closed:
+				return
+			}
+			_ = conn.Close()
+			return
+		}
+	}
 }
 
 func (s *SSH) Host() string {
 	return s.host
 }
 
+// DialUnix connects to the unix socket at path on the host, forwarded over the
+// SSH connection. The server must allow stream local forwarding.
+func (s *SSH) DialUnix(ctx context.Context, path string) (net.Conn, error) {
+	return s.client().DialContext(ctx, "unix", path)
+}
+
 func (s *SSH) Run(ctx context.Context, cmd string, options ...SessionOption) error {
 	opts := sessionOptions{
 		interactive: false,
@@ -113,38 +260,49 @@ func (s *SSH) Run(ctx context.Context, cmd string, options ...SessionOption) err
 		opt(&opts)
 	}
 
-	command, err := newCommand(s, cmd)
-	if err != nil {
-		return err
-	}
-	if err := command.execute(ctx, opts); err != nil {
-		return err
-	}
-	return nil
-}
+	for attempt := 1; ; attempt++ {
+		conn := s.client()
+		started, err := s.run(ctx, conn, cmd, opts)
+		if err == nil || !isConnectionError(err) {
+			return err
+		}
 
-func (s *SSH) WriteFile(path string, data []byte) error {
-	r := bytes.NewReader(data)
-	cmd := fmt.Sprintf("cat > %s", path)
-	// pass Background context to finish writing file
-	return s.Run(context.Background(), cmd, WithStdin(r))
-}
+		if reconnectErr := s.reconnect(conn); reconnectErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to reconnect: %w", reconnectErr))
+		}
 
-func (s *SSH) ReadFile(path string) ([]byte, error) {
-	var contents bytes.Buffer
-	cmd := fmt.Sprintf("cat %s", path)
-	// pass background context to finish reading file
-	err := s.Run(context.Background(), cmd, WithStdout(&contents))
-	if err != nil {
-		var pipeErr *pipeError
-		// if error is not copying stdout, return read contents
-		if errors.As(err, &pipeErr) && pipeErr.fd != fdStdout {
-			return contents.Bytes(), nil
+		// a command that was already sent to the host is only retried if it is safe to run twice
+		if started && (!opts.retry || opts.interactive) {
+			return err
+		}
+		if attempt >= s.retry.Attempts {
+			return err
+		}
+
+		delay := s.retry.delay(attempt)
+		logging.WarnHostf(s.host, "retrying command %q in %s: %s", cmd, delay, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		if r, ok := opts.stdout.(interface{ Reset() }); ok {
+			r.Reset()
 		}
-		return nil, err
 	}
+}
 
-	return contents.Bytes(), nil
+// run executes cmd once. The returned bool reports whether the command reached the host.
+func (s *SSH) run(ctx context.Context, conn *ssh.Client, cmd string, opts sessionOptions) (bool, error) {
+	command, err := newCommand(s, conn, cmd)
+	if err != nil {
+		return false, err
+	}
+	if err := command.execute(ctx, opts); err != nil {
+		return true, err
+	}
+	return true, nil
 }
 
 type SessionOption func(o *sessionOptions)
@@ -154,6 +312,7 @@ type sessionOptions struct {
 	stderr      io.Writer
 	stdin       io.Reader
 	interactive bool
+	retry       bool
 }
 
 func WithStdout(w io.Writer) SessionOption {
@@ -174,6 +333,15 @@ func WithStdin(r io.Reader) SessionOption {
 	}
 }
 
+// WithRetry marks the command as idempotent, so it is run again if the connection
+// drops while it is executing. Stdin is not replayed, and stdout is reset between
+// attempts only if the writer has a Reset method, like bytes.Buffer.
+func WithRetry() SessionOption {
+	return func(opts *sessionOptions) {
+		opts.retry = true
+	}
+}
+
 func WithPty() SessionOption {
 	return func(opts *sessionOptions) {
 		opts.interactive = true
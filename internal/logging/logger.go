@@ -88,6 +88,12 @@ func (l *Logger) SetLevel(level Level) {
 	l.level = level
 }
 
+func (l *Logger) Level() Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level
+}
+
 func Debug(msg string) {
 	Default().logMessage(LevelDebug, msg)
 }
@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lex-unix/faino/internal/logging"
+)
+
+type Event string
+
+const (
+	EventDeployStart   Event = "deploy.start"
+	EventDeploySuccess Event = "deploy.success"
+	EventDeployFailure Event = "deploy.failure"
+	EventRollback      Event = "rollback"
+)
+
+// Events lists every event webhooks can subscribe to.
+var Events = []Event{EventDeployStart, EventDeploySuccess, EventDeployFailure, EventRollback}
+
+const (
+	HostStatusDeployed   = "deployed"
+	HostStatusFailed     = "failed"
+	HostStatusRolledBack = "rolled back"
+)
+
+type HostOutcome struct {
+	Host   string `json:"host"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Message is sent as JSON body to webhooks. Text makes it compatible with
+// Slack incoming webhooks, other fields are for machine consumers.
+type Message struct {
+	Text          string        `json:"text"`
+	Event         Event         `json:"event"`
+	Service       string        `json:"service"`
+	Version       string        `json:"version"`
+	Commit        string        `json:"commit,omitempty"`
+	CommitMessage string        `json:"commit_message,omitempty"`
+	Operator      string        `json:"operator,omitempty"`
+	Duration      string        `json:"duration,omitempty"`
+	Hosts         []HostOutcome `json:"hosts,omitempty"`
+	Error         string        `json:"error,omitempty"`
+}
+
+type Notifier interface {
+	// Notify delivers message to subscribers. Delivery failures are logged, never returned,
+	// so that notifications cannot fail a deploy.
+	Notify(ctx context.Context, msg Message)
+}
+
+type Webhook struct {
+	URL string
+	// Events the webhook is subscribed to, all events if empty
+	Events []Event
+}
+
+type Webhooks struct {
+	client *http.Client
+	hooks  []Webhook
+}
+
+func New(hooks []Webhook, timeout time.Duration) *Webhooks {
+	return &Webhooks{
+		client: &http.Client{Timeout: timeout},
+		hooks:  hooks,
+	}
+}
+
+func (w *Webhooks) Notify(ctx context.Context, msg Message) {
+	if msg.Text == "" {
+		msg.Text = text(msg)
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		logging.Warnf("failed to encode notification: %s", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, hook := range w.hooks {
+		if len(hook.Events) > 0 && !slices.Contains(hook.Events, msg.Event) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.send(ctx, hook.URL, body); err != nil {
+				logging.Warnf("failed to send %s notification: %s", msg.Event, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Webhooks) send(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %s", res.Status)
+	}
+	return nil
+}
+
+// text renders a human readable summary of msg.
+func text(msg Message) string {
+	var action string
+	switch msg.Event {
+	case EventDeployStart:
+		action = "deploy started"
+	case EventDeploySuccess:
+		action = "deployed"
+	case EventDeployFailure:
+		action = "deploy failed"
+	case EventRollback:
+		action = "rolled back"
+	default:
+		action = string(msg.Event)
+	}
+
+	var sb bytes.Buffer
+	fmt.Fprintf(&sb, "%s %s: %s", msg.Service, msg.Version, action)
+	if msg.Operator != "" {
+		fmt.Fprintf(&sb, " by %s", msg.Operator)
+	}
+	if msg.Duration != "" {
+		fmt.Fprintf(&sb, " in %s", msg.Duration)
+	}
+	if msg.CommitMessage != "" {
+		fmt.Fprintf(&sb, "\n> %s", msg.CommitMessage)
+	}
+	if msg.Error != "" {
+		fmt.Fprintf(&sb, "\nerror: %s", msg.Error)
+	}
+	return sb.String()
+}
+
+// Noop drops all messages. It is used when no webhooks are configured.
+type Noop struct{}
+
+func (Noop) Notify(ctx context.Context, msg Message) {}
@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooksNotify(t *testing.T) {
+	t.Run("sends message to subscribed webhooks", func(t *testing.T) {
+		var mu sync.Mutex
+		received := make(map[string][]Message)
+		handler := func(name string) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				var msg Message
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+				mu.Lock()
+				received[name] = append(received[name], msg)
+				mu.Unlock()
+			}
+		}
+		all := httptest.NewServer(handler("all"))
+		defer all.Close()
+		failures := httptest.NewServer(handler("failures"))
+		defer failures.Close()
+
+		w := New([]Webhook{
+			{URL: all.URL},
+			{URL: failures.URL, Events: []Event{EventDeployFailure}},
+		}, time.Second)
+
+		w.Notify(context.Background(), Message{Event: EventDeploySuccess, Service: "web", Version: "v2", Operator: "alex"})
+
+		assert.Len(t, received["all"], 1)
+		assert.Empty(t, received["failures"])
+		assert.Equal(t, "web v2: deployed by alex", received["all"][0].Text)
+	})
+
+	t.Run("does not fail when webhook is unavailable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		w := New([]Webhook{{URL: server.URL}, {URL: "http://127.0.0.1:1"}}, time.Second)
+		assert.NotPanics(t, func() {
+			w.Notify(context.Background(), Message{Event: EventDeployStart})
+		})
+	})
+}
@@ -0,0 +1,67 @@
+package txman
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// barrier releases hosts of a transaction once every host reached it or finished.
+type barrier struct {
+	mu sync.Mutex
+	// waiting is the number of hosts that neither reached the barrier nor finished
+	waiting int
+	release chan struct{}
+}
+
+func newBarrier(hosts int) *barrier {
+	b := &barrier{waiting: hosts, release: make(chan struct{})}
+	if hosts == 0 {
+		close(b.release)
+	}
+	return b
+}
+
+func (b *barrier) leave() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.waiting--
+	if b.waiting == 0 {
+		close(b.release)
+	}
+}
+
+func (b *barrier) wait(ctx context.Context) error {
+	select {
+	case <-b.release:
+		// release and cancellation may happen together when the last host fails
+		return ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (tx *transaction) Barrier(ctx context.Context) error {
+	if tx.hasFailed {
+		return tx.err
+	}
+	if tx.arrived {
+		return errors.New("barrier can only be used once per transaction")
+	}
+	tx.arrived = true
+	tx.barrier.leave()
+	if err := tx.barrier.wait(ctx); err != nil {
+		tx.hasFailed = true
+		tx.err = errors.New("transaction cancelled")
+		return tx.err
+	}
+	return nil
+}
+
+// finish lets other hosts pass the barrier if tx never reached it, e.g. because it failed.
+func (tx *transaction) finish() {
+	if !tx.arrived {
+		tx.arrived = true
+		tx.barrier.leave()
+	}
+}
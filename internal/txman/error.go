@@ -0,0 +1,55 @@
+package txman
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HostError is returned by BeginTransaction and identifies the host
+// whose failure aborted the transaction.
+type HostError struct {
+	Host string
+	Err  error
+}
+
+func (e *HostError) Error() string {
+	return fmt.Sprintf("host %s: %s", e.Host, e.Err)
+}
+
+func (e *HostError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is returned by BeginTransaction if the transaction met its quorum
+// but failed on some hosts. The transaction is committed on the other hosts.
+type PartialError struct {
+	// Hosts is the number of hosts the transaction ran on
+	Hosts  int
+	Failed []*HostError
+}
+
+func (e *PartialError) Error() string {
+	failed := make([]string, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		failed = append(failed, f.Error())
+	}
+	return fmt.Sprintf("transaction succeeded on %d of %d hosts, failed on %s", e.Hosts-len(e.Failed), e.Hosts, strings.Join(failed, "; "))
+}
+
+// Unwrap returns errors of the failed hosts.
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// FailedHosts returns names of hosts the transaction failed on.
+func (e *PartialError) FailedHosts() []string {
+	hosts := make([]string, 0, len(e.Failed))
+	for _, f := range e.Failed {
+		hosts = append(hosts, f.Host)
+	}
+	return hosts
+}
@@ -0,0 +1,279 @@
+package txman
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+	"github.com/lex-unix/faino/internal/logging"
+)
+
+// DefaultJournalDir is where journals are kept on remote hosts.
+const DefaultJournalDir = "~/.faino/tx"
+
+const journalFileMode = 0600
+
+// JournalStep is a completed forward step of a transaction.
+type JournalStep struct {
+	// Name is the name of the step, see WithName
+	Name string `json:"name,omitempty"`
+	// Command is the forward command, empty if the step ran a callback
+	Command string `json:"command,omitempty"`
+	// Rollback is the shell command that undoes the step, empty if there is nothing to undo
+	Rollback string `json:"rollback,omitempty"`
+	// Replayable is false if the step was undone by a callback that cannot be
+	// restored from the journal, such a step has to be undone manually.
+	Replayable bool `json:"replayable"`
+}
+
+// Journal records progress of a transaction on a single host, so that the
+// transaction can be recovered if the process dies before it is committed or rolled back.
+type Journal struct {
+	ID        string        `json:"id"`
+	Host      string        `json:"host"`
+	StartedAt time.Time     `json:"started_at"`
+	Completed bool          `json:"completed"`
+	Steps     []JournalStep `json:"steps"`
+}
+
+// journal persists Journal of a transaction on its host. Write failures are
+// logged, they never fail the transaction. It is written even after the
+// transaction was cancelled, since recovery depends on it.
+type journal struct {
+	dir     string
+	client  sshexec.Service
+	data    Journal
+	created bool
+}
+
+func newTxID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405"), hex.EncodeToString(b))
+}
+
+func journalPath(dir, id string) string {
+	return path.Join(dir, id+".json")
+}
+
+func (j *journal) record(step JournalStep) {
+	if j == nil {
+		return
+	}
+	j.data.Steps = append(j.data.Steps, step)
+	j.write()
+}
+
+func (j *journal) complete() {
+	if j == nil {
+		return
+	}
+	j.data.Completed = true
+	j.write()
+}
+
+func (j *journal) write() {
+	if !j.created {
+		if err := j.client.MkdirAll(context.Background(), j.dir); err != nil {
+			logging.WarnHostf(j.client.Host(), "failed to create transaction journal: %s", err)
+			return
+		}
+		j.created = true
+	}
+	data, err := json.Marshal(j.data)
+	if err != nil {
+		logging.WarnHostf(j.client.Host(), "failed to encode transaction journal: %s", err)
+		return
+	}
+	if err := j.client.WriteFile(context.Background(), journalPath(j.dir, j.data.ID), data, journalFileMode); err != nil {
+		logging.WarnHostf(j.client.Host(), "failed to write transaction journal: %s", err)
+	}
+}
+
+// remove deletes the journal once the transaction was committed or rolled back.
+func (j *journal) remove() {
+	if j == nil || !j.created {
+		return
+	}
+	err := j.client.Remove(context.Background(), journalPath(j.dir, j.data.ID))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logging.WarnHostf(j.client.Host(), "failed to remove transaction journal: %s", err)
+	}
+}
+
+// EnableJournal makes transactions persist their progress on hosts under dir,
+// see Pending and Recover.
+func (m *txman) EnableJournal(dir string) {
+	m.journalDir = dir
+}
+
+func (m *txman) newJournal(id string, client sshexec.Service) *journal {
+	if m.journalDir == "" {
+		return nil
+	}
+	return &journal{
+		dir:    m.journalDir,
+		client: client,
+		data:   Journal{ID: id, Host: client.Host(), StartedAt: time.Now()},
+	}
+}
+
+// Pending returns journals of unfinished transactions found on hosts.
+func (m *txman) Pending(ctx context.Context) ([]Journal, error) {
+	if m.journalDir == "" {
+		return nil, errors.New("transaction journal is not enabled")
+	}
+
+	var mu sync.Mutex
+	journals := make([]Journal, 0)
+	err := m.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
+		found, err := m.readJournals(ctx, client)
+		if err != nil {
+			return fmt.Errorf("host %s: %w", client.Host(), err)
+		}
+		mu.Lock()
+		journals = append(journals, found...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(journals, func(a, b Journal) int {
+		if c := a.StartedAt.Compare(b.StartedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Host, b.Host)
+	})
+	return journals, nil
+}
+
+func (m *txman) readJournals(ctx context.Context, client sshexec.Service) ([]Journal, error) {
+	var out bytes.Buffer
+	err := client.Run(ctx, fmt.Sprintf("ls -1 %s 2>/dev/null || true", m.journalDir), sshexec.WithStdout(&out), sshexec.WithRetry())
+	if err != nil {
+		return nil, err
+	}
+
+	journals := make([]Journal, 0)
+	for _, name := range strings.Fields(out.String()) {
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		data, err := client.ReadFile(ctx, path.Join(m.journalDir, name))
+		if err != nil {
+			return nil, err
+		}
+		var j Journal
+		if err := json.Unmarshal(data, &j); err != nil {
+			return nil, fmt.Errorf("corrupted journal %s: %w", name, err)
+		}
+		journals = append(journals, j)
+	}
+	return journals, nil
+}
+
+// Recover finishes transaction id on every host that has its journal. By default
+// recorded rollback commands are replayed in reverse order. With forward, the
+// transaction is committed instead, which is only allowed if its forward pass
+// completed on every host.
+func (m *txman) Recover(ctx context.Context, id string, forward bool) error {
+	journals, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+
+	byHost := make(map[string]Journal)
+	for _, j := range journals {
+		if j.ID == id {
+			byHost[j.Host] = j
+		}
+	}
+	if len(byHost) == 0 {
+		return fmt.Errorf("transaction %s was not found on any host", id)
+	}
+
+	if forward {
+		incomplete := make([]string, 0)
+		for host, j := range byHost {
+			if !j.Completed {
+				incomplete = append(incomplete, host)
+			}
+		}
+		if len(incomplete) > 0 {
+			slices.Sort(incomplete)
+			return fmt.Errorf("transaction %s did not complete on %s, it can only be rolled back", id, strings.Join(incomplete, ", "))
+		}
+	}
+
+	var mu sync.Mutex
+	var report RollbackReport
+	err = m.Execute(ctx, func(ctx context.Context, client sshexec.Service) error {
+		j, ok := byHost[client.Host()]
+		if !ok {
+			return nil
+		}
+		if !forward {
+			results := replayRollback(ctx, client, j, func(e Event) {
+				e.TxID = id
+				m.notify(e)
+			})
+			mu.Lock()
+			report.Steps = append(report.Steps, results...)
+			mu.Unlock()
+			// keep journal, so that recovery can be retried
+			if (RollbackReport{Steps: results}).Failed() {
+				return nil
+			}
+		}
+		err := client.Remove(ctx, journalPath(m.journalDir, id))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if report.Failed() {
+		return &RollbackError{Report: report}
+	}
+	return nil
+}
+
+// replayRollback runs rollback commands of j in reverse order. Every step is
+// attempted, steps that cannot be replayed are reported as failed, so that the
+// operator can undo them manually.
+func replayRollback(ctx context.Context, client sshexec.Service, j Journal, notify func(Event)) []RollbackStepResult {
+	steps := make([]rollbackStep, 0, len(j.Steps))
+	for i, step := range j.Steps {
+		s := rollbackStep{step: i + 1, name: step.Name, unnamed: step.Name == "", rollback: step.Rollback}
+		switch {
+		case !step.Replayable:
+			s.fn = func(ctx context.Context, client sshexec.Service) error {
+				return errors.New("rollback was not recorded, undo the step manually")
+			}
+		case step.Rollback == "":
+			continue
+		default:
+			s.fn = func(ctx context.Context, client sshexec.Service) error {
+				logging.InfoHostf(client.Host(), "rolling back: %s", step.Rollback)
+				return client.Run(ctx, step.Rollback)
+			}
+		}
+		steps = append(steps, s)
+	}
+	return rollbackHost(ctx, client.Host(), client, steps, notify)
+}
@@ -0,0 +1,151 @@
+package txman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+)
+
+type EventKind string
+
+const (
+	// EventHostStarted is emitted for every host before its first step starts
+	EventHostStarted EventKind = "host_started"
+	EventStepStarted EventKind = "step_started"
+	// EventStepFinished is emitted once forward operation of a step returned, Err is set if it failed
+	EventStepFinished EventKind = "step_finished"
+	// EventTxFailed is emitted once per transaction, Host is the host whose failure aborted it
+	EventTxFailed EventKind = "tx_failed"
+	// EventHostFailed is emitted for a host that failed without failing the transaction, see WithQuorum
+	EventHostFailed EventKind = "host_failed"
+	// EventTxCommitted is emitted once the transaction committed, Err is PartialError if some hosts failed
+	EventTxCommitted EventKind = "tx_committed"
+	// EventRollbackStep is emitted after a step was undone, or failed to be undone, see Status
+	EventRollbackStep EventKind = "rollback_step"
+)
+
+// Event describes what a transaction is doing.
+type Event struct {
+	Kind EventKind
+	Time time.Time
+	// TxID identifies the transaction, it is the ID of its journal
+	TxID string
+	Host string
+	// Step is the 1-based position of the step on the host
+	Step int
+	Name string
+	// Command is the forward command of a step or rollback command of a rollback step
+	Command string
+	// Status is the outcome of a rollback step
+	Status   StepStatus
+	Duration time.Duration
+	Err      error
+
+	// unnamed is set if Name of the step falls back to its forward command
+	unnamed bool
+}
+
+// Observer receives events of every transaction of the Service it is registered
+// with. OnEvent is called concurrently from every host and must not block.
+type Observer interface {
+	OnEvent(Event)
+}
+
+// ObserverFunc adapts a function to Observer.
+type ObserverFunc func(Event)
+
+func (fn ObserverFunc) OnEvent(e Event) {
+	fn(e)
+}
+
+// Observe registers o to receive events of transactions.
+func (m *txman) Observe(o Observer) {
+	m.observersMu.Lock()
+	defer m.observersMu.Unlock()
+	m.observers = append(m.observers, o)
+}
+
+func (m *txman) notify(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	m.observersMu.RLock()
+	defer m.observersMu.RUnlock()
+	for _, o := range m.observers {
+		o.OnEvent(e)
+	}
+}
+
+// stepEvent converts e to a progress event, ok is false for events that do not
+// describe progress of a host.
+func (e Event) stepEvent() (StepEvent, bool) {
+	se := StepEvent{Host: e.Host, Step: e.Step, Name: e.Name, Duration: e.Duration, Err: e.Err}
+	switch {
+	case e.Kind == EventHostStarted:
+		se.State = ProgressPending
+	case e.Kind == EventStepStarted:
+		se.State = ProgressRunning
+	case e.Kind == EventStepFinished && e.Err != nil:
+		se.State = ProgressFailed
+	case e.Kind == EventStepFinished:
+		se.State = ProgressDone
+	default:
+		return StepEvent{}, false
+	}
+	return se, true
+}
+
+type jsonEvent struct {
+	Kind EventKind `json:"kind"`
+	Time time.Time `json:"time"`
+	TxID string    `json:"tx_id"`
+	Host string    `json:"host,omitempty"`
+	Step int       `json:"step,omitempty"`
+	Name string    `json:"name,omitempty"`
+	// Command is only set for rollback steps, forward commands may contain
+	// secrets, e.g. env of the app container
+	Command  string        `json:"command,omitempty"`
+	Status   StepStatus    `json:"status,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type jsonObserver struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONObserver returns an Observer that writes events to w as JSON lines.
+// Forward commands are left out, since events are often kept as CI artifacts.
+func NewJSONObserver(w io.Writer) Observer {
+	return &jsonObserver{enc: json.NewEncoder(w)}
+}
+
+func (o *jsonObserver) OnEvent(e Event) {
+	je := jsonEvent{
+		Kind:     e.Kind,
+		Time:     e.Time,
+		TxID:     e.TxID,
+		Host:     e.Host,
+		Step:     e.Step,
+		Name:     e.Name,
+		Status:   e.Status,
+		Duration: e.Duration,
+	}
+	if e.unnamed {
+		je.Name = fmt.Sprintf("step %d", e.Step)
+	}
+	if e.Kind == EventRollbackStep {
+		je.Command = e.Command
+	}
+	if e.Err != nil {
+		je.Error = sshexec.RedactCommand(e.Err)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_ = o.enc.Encode(je)
+}
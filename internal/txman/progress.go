@@ -0,0 +1,36 @@
+package txman
+
+import "time"
+
+// ProgressState is the state of a host or of a step within a transaction.
+type ProgressState string
+
+const (
+	ProgressPending ProgressState = "pending"
+	ProgressRunning ProgressState = "running"
+	ProgressDone    ProgressState = "done"
+	ProgressFailed  ProgressState = "failed"
+)
+
+// StepEvent reports progress of a transaction on a single host. An event with
+// zero Step is emitted for every host before its first step starts.
+type StepEvent struct {
+	Host string
+	// Step is the 1-based position of the step on the host
+	Step  int
+	Name  string
+	State ProgressState
+	// Duration is how long the step ran, set once it is done or failed
+	Duration time.Duration
+	Err      error
+}
+
+// ProgressFunc receives step events. It is called concurrently from every host.
+type ProgressFunc func(StepEvent)
+
+// WithProgress reports progress of every step of the transaction to fn.
+func WithProgress(fn ProgressFunc) TxOption {
+	return func(o *txOptions) {
+		o.progress = fn
+	}
+}
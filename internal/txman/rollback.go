@@ -0,0 +1,126 @@
+package txman
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lex-unix/faino/internal/exec/sshexec"
+)
+
+type StepStatus string
+
+const (
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+	// StepSkipped is a step that was not attempted because rollback was canceled
+	StepSkipped StepStatus = "skipped"
+)
+
+// RollbackStepResult is the outcome of undoing a single step on a host.
+type RollbackStepResult struct {
+	Host string
+	// Step is the 1-based position of the step in the forward pass
+	Step int
+	Name string
+	// Command is the shell command that undoes the step, empty if it is not known
+	Command string
+	Status  StepStatus
+	Err     error
+}
+
+// RollbackReport holds results of every rollback step on every host.
+type RollbackReport struct {
+	Steps []RollbackStepResult
+}
+
+// Failed reports whether any step failed or was skipped.
+func (r RollbackReport) Failed() bool {
+	for _, s := range r.Steps {
+		if s.Status != StepSucceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// Manual returns steps that an operator has to undo manually, ordered by host
+// and in the order they have to be run.
+func (r RollbackReport) Manual() []RollbackStepResult {
+	manual := make([]RollbackStepResult, 0)
+	for _, s := range r.Steps {
+		if s.Status != StepSucceeded {
+			manual = append(manual, s)
+		}
+	}
+	sort.SliceStable(manual, func(i, j int) bool {
+		if manual[i].Host != manual[j].Host {
+			return manual[i].Host < manual[j].Host
+		}
+		return manual[i].Step > manual[j].Step
+	})
+	return manual
+}
+
+// RollbackError is returned by RollbackFunc and Recover if rollback did not fully succeed.
+type RollbackError struct {
+	Report RollbackReport
+}
+
+func (e *RollbackError) Error() string {
+	manual := e.Report.Manual()
+	hosts := make([]string, 0)
+	for _, s := range manual {
+		if len(hosts) == 0 || hosts[len(hosts)-1] != s.Host {
+			hosts = append(hosts, s.Host)
+		}
+	}
+	return fmt.Sprintf("rollback incomplete: %d steps were not undone on %s", len(manual), strings.Join(hosts, ", "))
+}
+
+type rollbackStep struct {
+	step     int
+	name     string
+	unnamed  bool
+	fn       Callback
+	rollback string
+}
+
+// rollbackHost undoes steps of tx in reverse order. Every step is attempted even
+// if previous ones failed, steps left when ctx is canceled are skipped. Result of
+// every step is reported to notify.
+func rollbackHost(ctx context.Context, host string, client sshexec.Service, steps []rollbackStep, notify func(Event)) []RollbackStepResult {
+	results := make([]RollbackStepResult, 0, len(steps))
+	for i := len(steps) - 1; i >= 0; i-- {
+		s := steps[i]
+		result := RollbackStepResult{Host: host, Step: s.step, Name: s.name, Command: s.rollback}
+		started := time.Now()
+		switch {
+		case ctx.Err() != nil:
+			result.Status = StepSkipped
+			result.Err = ctx.Err()
+		default:
+			if err := s.fn(ctx, client); err != nil {
+				result.Status = StepFailed
+				result.Err = err
+			} else {
+				result.Status = StepSucceeded
+			}
+		}
+		results = append(results, result)
+		notify(Event{
+			Kind:     EventRollbackStep,
+			Host:     host,
+			Step:     result.Step,
+			Name:     result.Name,
+			Command:  result.Command,
+			Status:   result.Status,
+			Duration: time.Since(started),
+			Err:      result.Err,
+			unnamed:  s.unnamed,
+		})
+	}
+	return results
+}
@@ -3,6 +3,7 @@ package txman
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/lex-unix/faino/internal/exec/sshexec"
@@ -12,8 +13,12 @@ import (
 type SSHServiceStub struct {
 	hostName      string
 	RunFunc       func(ctx context.Context, cmd string, options ...sshexec.SessionOption) error
-	WriteFileFunc func(path string, data []byte) error
+	WriteFileFunc func(path string, data []byte, perm os.FileMode) error
 	ReadFileFunc  func(path string) ([]byte, error)
+	StatFunc      func(path string) (os.FileInfo, error)
+	RemoveFunc    func(path string) error
+	RenameFunc    func(oldpath, newpath string) error
+	MkdirAllFunc  func(path string) error
 }
 
 // NewMockSSHLikeService creates a new mock service.
@@ -38,7 +43,7 @@ func (stub *SSHServiceStub) Run(ctx context.Context, cmd string, options ...sshe
 }
 
 // ReadFile simulates reading a file from the in-memory file system.
-func (stub *SSHServiceStub) ReadFile(path string) ([]byte, error) {
+func (stub *SSHServiceStub) ReadFile(_ context.Context, path string) ([]byte, error) {
 	if stub.WriteFileFunc != nil {
 		return stub.ReadFileFunc(path)
 	}
@@ -46,14 +51,46 @@ func (stub *SSHServiceStub) ReadFile(path string) ([]byte, error) {
 }
 
 // WriteFile simulates writing a file to the in-memory file system.
-func (stub *SSHServiceStub) WriteFile(path string, data []byte) error {
+func (stub *SSHServiceStub) WriteFile(_ context.Context, path string, data []byte, perm os.FileMode) error {
 	if stub.WriteFileFunc != nil {
-		return stub.WriteFileFunc(path, data)
+		return stub.WriteFileFunc(path, data, perm)
 	}
 	return fmt.Errorf("WriteFile not implemented")
 }
 
+// Stat simulates reading file info.
+func (stub *SSHServiceStub) Stat(_ context.Context, path string) (os.FileInfo, error) {
+	if stub.StatFunc != nil {
+		return stub.StatFunc(path)
+	}
+	return nil, fmt.Errorf("Stat not implemented")
+}
+
+// Remove simulates removing a file.
+func (stub *SSHServiceStub) Remove(_ context.Context, path string) error {
+	if stub.RemoveFunc != nil {
+		return stub.RemoveFunc(path)
+	}
+	return fmt.Errorf("Remove not implemented")
+}
+
+// Rename simulates renaming a file.
+func (stub *SSHServiceStub) Rename(_ context.Context, oldpath, newpath string) error {
+	if stub.RenameFunc != nil {
+		return stub.RenameFunc(oldpath, newpath)
+	}
+	return fmt.Errorf("Rename not implemented")
+}
+
 // Host returns the configured hostname.
 func (stub *SSHServiceStub) Host() string {
 	return stub.hostName
 }
+
+// MkdirAll simulates creating a directory.
+func (stub *SSHServiceStub) MkdirAll(_ context.Context, path string) error {
+	if stub.MkdirAllFunc != nil {
+		return stub.MkdirAllFunc(path)
+	}
+	return fmt.Errorf("MkdirAll not implemented")
+}
@@ -3,6 +3,8 @@ package txman
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/lex-unix/faino/internal/exec/sshexec"
 )
@@ -13,22 +15,95 @@ type Transaction interface {
 	// considered failed, and this error will be propagated to trigger rollback.
 	// The rollbackFn will be executed if forwardFn succeeded but a later
 	// operation (on this host or another) fails.
-	Do(ctx context.Context, forwardFn Callback, rollbackFn Callback) error
+	Do(ctx context.Context, forwardFn Callback, rollbackFn Callback, options ...StepOption) error
 
 	// Run is a convenience wrapper around Do for simple command execution.
 	// It assumes a standard way to run a command via sshexec.Service.
-	Run(ctx context.Context, forwardCmd string, rollbackCmd string) error
+	Run(ctx context.Context, forwardCmd string, rollbackCmd string, options ...StepOption) error
+
+	// Barrier waits until every host of the transaction reached it, hosts that
+	// failed or finished without reaching it are not waited for. It splits the
+	// transaction into phases, e.g. to switch all hosts together once each of
+	// them is prepared. It can be used once per transaction.
+	Barrier(ctx context.Context) error
+
+	// Host returns name of the host the transaction runs on.
+	Host() string
+}
+
+type step struct {
+	journal JournalStep
+	timeout time.Duration
+}
+
+// name returns name of the step, falling back to its command or position.
+func (s step) name(n int) string {
+	switch {
+	case s.journal.Name != "":
+		return s.journal.Name
+	case s.journal.Command != "":
+		return s.journal.Command
+	default:
+		return fmt.Sprintf("step %d", n)
+	}
+}
+
+// StepOption configures a single step of a transaction.
+type StepOption func(*step)
+
+// WithName names the step in progress events, journals and rollback reports.
+func WithName(name string) StepOption {
+	return func(s *step) {
+		s.journal.Name = name
+	}
+}
+
+// WithCommand records forward command of the step.
+func WithCommand(cmd string) StepOption {
+	return func(s *step) {
+		s.journal.Command = cmd
+	}
+}
+
+// WithRollbackCommand records shell command equivalent to the rollback callback,
+// so that the step can be rolled back from the journal after a crash.
+func WithRollbackCommand(cmd string) StepOption {
+	return func(s *step) {
+		s.journal.Rollback = cmd
+		s.journal.Replayable = true
+	}
+}
+
+// WithTimeout limits how long forward operation of the step may run. It overrides
+// default step timeout of the transaction, zero disables the limit.
+func WithTimeout(d time.Duration) StepOption {
+	return func(s *step) {
+		s.timeout = d
+	}
 }
 
 type transaction struct {
 	client      sshexec.Service
 	hostName    string
-	rollbackFns []Callback
-	hasFailed   bool
-	err         error
+	rollbackFns []rollbackStep
+	// steps is the number of completed forward steps
+	steps       int
+	journal     *journal
+	stepTimeout time.Duration
+	// notify reports events of the transaction to observers of txman
+	notify  func(Event)
+	barrier *barrier
+	// arrived is set once the host reached the barrier or finished
+	arrived   bool
+	hasFailed bool
+	err       error
 }
 
-func (tx *transaction) Do(ctx context.Context, forwardFn Callback, rollbackFn Callback) error {
+func (tx *transaction) Host() string {
+	return tx.hostName
+}
+
+func (tx *transaction) Do(ctx context.Context, forwardFn Callback, rollbackFn Callback, options ...StepOption) error {
 	if tx.hasFailed {
 		return tx.err
 	}
@@ -40,21 +115,57 @@ func (tx *transaction) Do(ctx context.Context, forwardFn Callback, rollbackFn Ca
 	default:
 	}
 
-	err := forwardFn(ctx, tx.client)
+	s := step{
+		journal: JournalStep{Replayable: rollbackFn == nil},
+		timeout: tx.stepTimeout,
+	}
+	for _, opt := range options {
+		opt(&s)
+	}
+
+	n := tx.steps + 1
+	name := s.name(n)
+	unnamed := s.journal.Name == ""
+	tx.notify(Event{Kind: EventStepStarted, Host: tx.hostName, Step: n, Name: name, Command: s.journal.Command, unnamed: unnamed})
+	started := time.Now()
+	err := tx.forward(ctx, forwardFn, s.timeout)
+	tx.notify(Event{Kind: EventStepFinished, Host: tx.hostName, Step: n, Name: name, Command: s.journal.Command, Duration: time.Since(started), Err: err, unnamed: unnamed})
 	if err != nil {
 		tx.hasFailed = true
 		tx.err = err
 		return tx.err
 	}
 
+	tx.steps = n
 	if rollbackFn != nil {
-		tx.rollbackFns = append(tx.rollbackFns, rollbackFn)
+		tx.rollbackFns = append(tx.rollbackFns, rollbackStep{step: n, name: name, unnamed: unnamed, fn: rollbackFn, rollback: s.journal.Rollback})
 	}
 
+	tx.journal.record(s.journal)
+
 	return nil
 }
 
-func (tx *transaction) Run(ctx context.Context, forwardCmd string, rollbackCmd string) error {
+func (tx *transaction) forward(ctx context.Context, forwardFn Callback, timeout time.Duration) error {
+	if timeout <= 0 {
+		return forwardFn(ctx, tx.client)
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := forwardFn(stepCtx, tx.client)
+	// deadline of the whole transaction is reported as is
+	if errors.Is(stepCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		// a callback may return nil although it was cut short
+		if err == nil {
+			err = stepCtx.Err()
+		}
+		return fmt.Errorf("step timed out after %s: %w", timeout, err)
+	}
+	return err
+}
+
+func (tx *transaction) Run(ctx context.Context, forwardCmd string, rollbackCmd string, options ...StepOption) error {
 	var forwardFn Callback = func(ctx context.Context, client sshexec.Service) error {
 		return client.Run(ctx, forwardCmd)
 	}
@@ -64,5 +175,6 @@ func (tx *transaction) Run(ctx context.Context, forwardCmd string, rollbackCmd s
 		}
 		return client.Run(ctx, rollbackCmd)
 	}
-	return tx.Do(ctx, forwardFn, rollbackFn)
+	options = append([]StepOption{WithCommand(forwardCmd), WithRollbackCommand(rollbackCmd)}, options...)
+	return tx.Do(ctx, forwardFn, rollbackFn, options...)
 }
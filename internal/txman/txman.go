@@ -3,7 +3,10 @@ package txman
 import (
 	"context"
 	"errors"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/lex-unix/faino/internal/exec/sshexec"
 	"github.com/lex-unix/faino/internal/logging"
@@ -28,25 +31,38 @@ type Service interface {
 	// BeginTransaction executes passed callback on each remote host in transaction.
 	// If transaction succeeded, the returned error is nil and rollback function is nil or no-op
 	// If a command fails or ctx is canceled, returned error is not nil and rollback function can be called
-	// to perform a rollback.
-	BeginTransaction(ctx context.Context, callback TxCallback) (RollbackFunc, error)
+	// to perform a rollback. If the transaction met its quorum, see WithQuorum, but some hosts
+	// failed, returned error is PartialError and rollback function undoes only the failed hosts.
+	BeginTransaction(ctx context.Context, callback TxCallback, options ...TxOption) (RollbackFunc, error)
 
 	// Execute runs a provided callback on a each remote host.
 	// In case of a command failure, it will continue execution on other hosts.
 	Execute(ctx context.Context, callback Callback) error
+
+	// Pending returns journals of transactions that were neither committed nor
+	// rolled back, e.g. because the process was killed.
+	Pending(ctx context.Context) ([]Journal, error)
+
+	// Recover rolls back transaction id from its journals, or commits it if forward is set.
+	Recover(ctx context.Context, id string, forward bool) error
+
+	// Observe registers o to receive events of every transaction, see Event.
+	Observe(o Observer)
 }
 
 type txman struct {
 	// clients stores connections to remote host
 	clients map[string]sshexec.Service
+	// journalDir is where transactions are journaled on hosts, journaling is disabled if empty
+	journalDir string
 
-	wg sync.WaitGroup
+	observersMu sync.RWMutex
+	observers   []Observer
 }
 
 func New(conns ...sshexec.Service) *txman {
 	m := &txman{
 		clients: make(map[string]sshexec.Service, len(conns)),
-		wg:      sync.WaitGroup{},
 	}
 	for _, conn := range conns {
 		m.clients[conn.Host()] = conn
@@ -55,107 +71,191 @@ func New(conns ...sshexec.Service) *txman {
 	return m
 }
 
-func (m *txman) BeginTransaction(ctx context.Context, callback TxCallback) (RollbackFunc, error) {
+type txOptions struct {
+	stepTimeout time.Duration
+	progress    ProgressFunc
+	quorum      int
+}
+
+// TxOption configures a transaction.
+type TxOption func(*txOptions)
+
+// WithStepTimeout limits every step of the transaction that does not declare its own timeout.
+func WithStepTimeout(d time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.stepTimeout = d
+	}
+}
+
+// WithQuorum lets the transaction commit if at least percent of hosts succeeded.
+// Failed hosts are not rolled back together with the others, BeginTransaction
+// returns PartialError and its rollback function undoes only the failed hosts.
+// Zero and 100 require every host to succeed.
+func WithQuorum(percent int) TxOption {
+	return func(o *txOptions) {
+		o.quorum = percent
+	}
+}
+
+// allowedFailures returns how many of hosts may fail without failing the transaction.
+func (o txOptions) allowedFailures(hosts int) int {
+	if o.quorum <= 0 || o.quorum >= 100 {
+		return 0
+	}
+	required := max((hosts*o.quorum+99)/100, 1)
+	return hosts - required
+}
+
+func (m *txman) BeginTransaction(ctx context.Context, callback TxCallback, options ...TxOption) (RollbackFunc, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	var opts txOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	id := newTxID()
+	notify := func(e Event) {
+		e.TxID = id
+		m.notify(e)
+		if opts.progress == nil {
+			return
+		}
+		if se, ok := e.stepEvent(); ok {
+			opts.progress(se)
+		}
+	}
+
+	b := newBarrier(len(m.clients))
 	txs := make([]*transaction, 0, len(m.clients))
 	for host, client := range m.clients {
 		tx := &transaction{
-			client:   client,
-			hostName: host,
+			barrier:     b,
+			client:      client,
+			hostName:    host,
+			journal:     m.newJournal(id, client),
+			stepTimeout: opts.stepTimeout,
+			notify:      notify,
 		}
 		txs = append(txs, tx)
 	}
 
+	allowed := opts.allowedFailures(len(txs))
 	var txErr error
+	var failed []*transaction
 	var txErrMu sync.Mutex
+	var wg sync.WaitGroup
 	for _, tx := range txs {
-		m.wg.Add(1)
+		wg.Add(1)
 		go func() {
-			defer m.wg.Done()
+			defer wg.Done()
+			tx.notify(Event{Kind: EventHostStarted, Host: tx.hostName})
 			err := callback(ctx, tx)
+			// hosts waiting at the barrier are released only after the failure
+			// is recorded and ctx is cancelled, so that they don't commit
+			defer tx.finish()
+			if err == nil {
+				tx.journal.complete()
+			}
 			txErrMu.Lock()
-			if err != nil && txErr == nil {
-				txErr = err
+			defer txErrMu.Unlock()
+			if err == nil {
+				return
+			}
+			tx.err = err
+			failed = append(failed, tx)
+			switch {
+			case txErr != nil:
+			case len(failed) > allowed:
+				txErr = &HostError{Host: tx.hostName, Err: err}
+				notify(Event{Kind: EventTxFailed, Host: tx.hostName, Err: err})
 				cancel()
+			default:
+				notify(Event{Kind: EventHostFailed, Host: tx.hostName, Err: err})
 			}
-			txErrMu.Unlock()
 		}()
 	}
 
-	m.wg.Wait()
+	wg.Wait()
 
-	rollbackFn := func(ctx context.Context) error {
+	if txErr != nil {
+		return rollbackTxs(txs, notify), txErr
+	}
+
+	// transaction is committed, nothing left to recover except failed hosts
+	for _, tx := range txs {
+		if !slices.Contains(failed, tx) {
+			tx.journal.remove()
+		}
+	}
+
+	if len(failed) > 0 {
+		slices.SortFunc(failed, func(a, b *transaction) int { return strings.Compare(a.hostName, b.hostName) })
+		partialErr := &PartialError{Hosts: len(txs)}
+		for _, tx := range failed {
+			partialErr.Failed = append(partialErr.Failed, &HostError{Host: tx.hostName, Err: tx.err})
+		}
+		notify(Event{Kind: EventTxCommitted, Err: partialErr})
+		return rollbackTxs(failed, notify), partialErr
+	}
+
+	notify(Event{Kind: EventTxCommitted})
+	return rollbackTxs(txs, notify), nil
+}
+
+// rollbackTxs returns a function that rolls back txs on their hosts concurrently.
+func rollbackTxs(txs []*transaction, notify func(Event)) RollbackFunc {
+	return func(ctx context.Context) error {
 		var wg sync.WaitGroup
-		rollbackErrCh := make(chan error, len(txs))
+		var mu sync.Mutex
+		var report RollbackReport
 		for _, tx := range txs {
 			if len(tx.rollbackFns) == 0 {
+				tx.journal.remove()
 				continue
 			}
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				for i := len(tx.rollbackFns) - 1; i >= 0; i-- {
-					select {
-					case <-ctx.Done():
-						return
-					default:
-					}
-					rollback := tx.rollbackFns[i]
-					err := rollback(ctx, tx.client)
-					if err != nil {
-						rollbackErrCh <- err
-						return
-					}
+				results := rollbackHost(ctx, tx.hostName, tx.client, tx.rollbackFns, notify)
+				mu.Lock()
+				report.Steps = append(report.Steps, results...)
+				mu.Unlock()
+				if !(RollbackReport{Steps: results}).Failed() {
+					tx.journal.remove()
 				}
 			}()
 		}
+		wg.Wait()
 
-		go func() {
-			wg.Wait()
-			close(rollbackErrCh)
-		}()
-
-		var err error
-		for rollbackErr := range rollbackErrCh {
-			err = errors.Join(err, rollbackErr)
-		}
-		if err != nil {
-			return err
+		if report.Failed() {
+			return &RollbackError{Report: report}
 		}
 		return nil
 	}
-
-	if txErr != nil {
-		return rollbackFn, txErr
-	}
-
-	return rollbackFn, nil
 }
 
+// Execute runs callback on every host and waits for all of them to finish,
+// even if some failed. Errors of failed hosts are joined.
 func (m *txman) Execute(ctx context.Context, callback Callback) error {
-	errCh := make(chan error, len(m.clients))
-	m.wg.Add(len(m.clients))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
 	for host, client := range m.clients {
+		wg.Add(1)
 		go func() {
-			defer m.wg.Done()
+			defer wg.Done()
 			err := callback(ctx, client)
 			if err != nil {
 				logging.ErrorHost(host, "failed to run command")
-				errCh <- err
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
 			}
 		}()
 	}
+	wg.Wait()
 
-	go func() {
-		m.wg.Wait()
-		close(errCh)
-	}()
-
-	if err := <-errCh; err != nil {
-		return err
-	}
-
-	return nil
+	return errors.Join(errs...)
 }
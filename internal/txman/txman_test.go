@@ -1,12 +1,17 @@
 package txman
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/lex-unix/faino/internal/exec/sshexec"
 	"github.com/stretchr/testify/assert"
@@ -107,6 +112,10 @@ func TestBeginTransactin(t *testing.T) {
 		})
 
 		assert.Error(t, err)
+		var hostErr *HostError
+		if assert.ErrorAs(t, err, &hostErr) {
+			assert.Equal(t, "host1", hostErr.Host)
+		}
 
 		ctx = context.Background()
 		err = rollback(ctx)
@@ -150,3 +159,424 @@ func TestBeginTransactin(t *testing.T) {
 		assert.Equal(t, "rollback 1", rollbackCmds[1])
 	})
 }
+
+func TestTransactionJournal(t *testing.T) {
+	newClient := func(host string, failOn string) (*SSHServiceStub, map[string][]byte) {
+		var mu sync.Mutex
+		files := make(map[string][]byte)
+		client := NewMockSSHLikeService(host)
+		client.RunFunc = func(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+			if cmd == failOn {
+				return errors.New("command failed")
+			}
+			return nil
+		}
+		client.MkdirAllFunc = func(path string) error { return nil }
+		client.WriteFileFunc = func(path string, data []byte, perm os.FileMode) error {
+			mu.Lock()
+			defer mu.Unlock()
+			files[path] = data
+			return nil
+		}
+		client.RemoveFunc = func(path string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			delete(files, path)
+			return nil
+		}
+		return client, files
+	}
+
+	callback := func(ctx context.Context, tx Transaction) error {
+		if err := tx.Run(ctx, "command 1", "rollback 1"); err != nil {
+			return err
+		}
+		return tx.Run(ctx, "command 2", "rollback 2")
+	}
+
+	t.Run("removes journal when transaction is committed", func(t *testing.T) {
+		client, files := newClient("host1", "")
+		m := New(client)
+		m.EnableJournal(DefaultJournalDir)
+
+		_, err := m.BeginTransaction(context.Background(), callback)
+
+		assert.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("keeps journal of failed transaction until it is rolled back", func(t *testing.T) {
+		client, files := newClient("host1", "command 2")
+		m := New(client)
+		m.EnableJournal(DefaultJournalDir)
+
+		rollback, err := m.BeginTransaction(context.Background(), callback)
+		assert.Error(t, err)
+		assert.Len(t, files, 1)
+
+		for _, data := range files {
+			var j Journal
+			assert.NoError(t, json.Unmarshal(data, &j))
+			assert.Equal(t, "host1", j.Host)
+			assert.False(t, j.Completed)
+			assert.Equal(t, []JournalStep{{Command: "command 1", Rollback: "rollback 1", Replayable: true}}, j.Steps)
+		}
+
+		assert.NoError(t, rollback(context.Background()))
+		assert.Empty(t, files)
+	})
+}
+
+func TestStepTimeout(t *testing.T) {
+	client := NewMockSSHLikeService("host1")
+	client.RunFunc = func(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+		return nil
+	}
+	m := New(client)
+
+	t.Run("default step timeout fails slow step", func(t *testing.T) {
+		_, err := m.BeginTransaction(context.Background(), func(ctx context.Context, tx Transaction) error {
+			return tx.Run(ctx, "slow command", "")
+		}, WithStepTimeout(50*time.Millisecond))
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "step timed out after 50ms")
+	})
+
+	t.Run("step timeout overrides default", func(t *testing.T) {
+		_, err := m.BeginTransaction(context.Background(), func(ctx context.Context, tx Transaction) error {
+			return tx.Run(ctx, "slow command", "", WithTimeout(time.Second))
+		}, WithStepTimeout(50*time.Millisecond))
+
+		assert.NoError(t, err)
+	})
+}
+
+// hangingClient runs commands until ctx is done and, like a command that
+// exits cleanly on SIGTERM, reports no error.
+type hangingClient struct {
+	sshexec.Service
+}
+
+func (c hangingClient) Host() string {
+	return "host1"
+}
+
+func (c hangingClient) Run(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestStepTimeoutWithoutError(t *testing.T) {
+	m := New(hangingClient{})
+
+	_, err := m.BeginTransaction(context.Background(), func(ctx context.Context, tx Transaction) error {
+		return tx.Run(ctx, "docker pull app:v1", "", WithTimeout(50*time.Millisecond))
+	})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "step timed out after 50ms")
+}
+
+func TestRollbackContinuesOnError(t *testing.T) {
+	var mu sync.Mutex
+	rollbackCmds := make([]string, 0)
+	client := NewMockSSHLikeService("host1")
+	client.RunFunc = func(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+		if cmd == "command 3" || cmd == "rollback 2" {
+			return errors.New("command failed")
+		}
+		if strings.HasPrefix(cmd, "rollback") {
+			mu.Lock()
+			rollbackCmds = append(rollbackCmds, cmd)
+			mu.Unlock()
+		}
+		return nil
+	}
+
+	m := New(client)
+	rollback, err := m.BeginTransaction(context.Background(), func(ctx context.Context, tx Transaction) error {
+		for i := 1; i <= 3; i++ {
+			if err := tx.Run(ctx, fmt.Sprintf("command %d", i), fmt.Sprintf("rollback %d", i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.Error(t, err)
+
+	err = rollback(context.Background())
+
+	var rollbackErr *RollbackError
+	if assert.ErrorAs(t, err, &rollbackErr) {
+		assert.Equal(t, []string{"rollback 1"}, rollbackCmds)
+		assert.Equal(t, StepFailed, rollbackErr.Report.Steps[0].Status)
+		assert.Equal(t, 2, rollbackErr.Report.Steps[0].Step)
+		assert.Equal(t, StepSucceeded, rollbackErr.Report.Steps[1].Status)
+		manual := rollbackErr.Report.Manual()
+		if assert.Len(t, manual, 1) {
+			assert.Equal(t, "rollback 2", manual[0].Command)
+		}
+	}
+}
+
+func TestStepProgress(t *testing.T) {
+	client := NewMockSSHLikeService("host1")
+	client.RunFunc = func(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+		if cmd == "command 2" {
+			return errors.New("command failed")
+		}
+		return nil
+	}
+
+	var mu sync.Mutex
+	events := make([]StepEvent, 0)
+	progress := func(e StepEvent) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}
+
+	m := New(client)
+	_, err := m.BeginTransaction(context.Background(), func(ctx context.Context, tx Transaction) error {
+		if err := tx.Run(ctx, "command 1", "", WithName("first")); err != nil {
+			return err
+		}
+		return tx.Run(ctx, "command 2", "")
+	}, WithProgress(progress))
+	assert.Error(t, err)
+
+	states := make([]ProgressState, 0, len(events))
+	for _, e := range events {
+		states = append(states, e.State)
+	}
+	assert.Equal(t, []ProgressState{ProgressPending, ProgressRunning, ProgressDone, ProgressRunning, ProgressFailed}, states)
+	assert.Equal(t, "first", events[2].Name)
+	assert.Equal(t, 1, events[2].Step)
+	assert.Equal(t, "command 2", events[4].Name)
+	assert.Equal(t, 2, events[4].Step)
+	assert.Error(t, events[4].Err)
+}
+
+func TestObserver(t *testing.T) {
+	client := NewMockSSHLikeService("host1")
+	client.RunFunc = func(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+		if cmd == "command 2 --env SECRET=value" {
+			return &sshexec.CommandError{Host: "host1", Command: cmd, Code: 1}
+		}
+		return nil
+	}
+
+	var mu sync.Mutex
+	events := make([]Event, 0)
+	var out bytes.Buffer
+
+	m := New(client)
+	m.Observe(ObserverFunc(func(e Event) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	m.Observe(NewJSONObserver(&out))
+
+	rollback, err := m.BeginTransaction(context.Background(), func(ctx context.Context, tx Transaction) error {
+		if err := tx.Run(ctx, "command 1", "rollback 1"); err != nil {
+			return err
+		}
+		return tx.Run(ctx, "command 2 --env SECRET=value", "rollback 2")
+	})
+	assert.Error(t, err)
+	assert.NoError(t, rollback(context.Background()))
+
+	kinds := make([]EventKind, 0, len(events))
+	for _, e := range events {
+		kinds = append(kinds, e.Kind)
+		assert.Equal(t, events[0].TxID, e.TxID)
+	}
+	assert.Equal(t, []EventKind{
+		EventHostStarted,
+		EventStepStarted,
+		EventStepFinished,
+		EventStepStarted,
+		EventStepFinished,
+		EventTxFailed,
+		EventRollbackStep,
+	}, kinds)
+	assert.Equal(t, "host1", events[5].Host)
+	assert.Equal(t, "rollback 1", events[6].Command)
+	assert.Equal(t, StepSucceeded, events[6].Status)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, len(events)) {
+		assert.Contains(t, lines[5], `"kind":"tx_failed"`)
+		assert.Contains(t, lines[5], `"error":"command \u003credacted\u003e on host1 failed with exit code 1"`)
+		assert.Contains(t, lines[6], `"command":"rollback 1"`)
+	}
+	assert.NotContains(t, out.String(), "SECRET")
+}
+
+func TestQuorum(t *testing.T) {
+	newClients := func(rollbacks map[string][]string, mu *sync.Mutex) []sshexec.Service {
+		clients := make([]sshexec.Service, 0, 3)
+		for _, host := range []string{"host1", "host2", "host3"} {
+			client := NewMockSSHLikeService(host)
+			client.RunFunc = func(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+				if host == "host3" && cmd == "command 2" {
+					return errors.New("command failed")
+				}
+				if strings.HasPrefix(cmd, "rollback") {
+					mu.Lock()
+					rollbacks[host] = append(rollbacks[host], cmd)
+					mu.Unlock()
+				}
+				return nil
+			}
+			clients = append(clients, client)
+		}
+		return clients
+	}
+	callback := func(ctx context.Context, tx Transaction) error {
+		if err := tx.Run(ctx, "command 1", "rollback 1"); err != nil {
+			return err
+		}
+		return tx.Run(ctx, "command 2", "rollback 2")
+	}
+
+	t.Run("commits on hosts that succeeded if quorum is met", func(t *testing.T) {
+		var mu sync.Mutex
+		rollbacks := make(map[string][]string)
+		m := New(newClients(rollbacks, &mu)...)
+
+		rollback, err := m.BeginTransaction(context.Background(), callback, WithQuorum(60))
+
+		var partialErr *PartialError
+		if assert.ErrorAs(t, err, &partialErr) {
+			assert.Equal(t, 3, partialErr.Hosts)
+			assert.Equal(t, []string{"host3"}, partialErr.FailedHosts())
+		}
+		assert.NoError(t, rollback(context.Background()))
+		assert.Equal(t, map[string][]string{"host3": {"rollback 1"}}, rollbacks)
+	})
+
+	t.Run("rolls back every host if quorum is not met", func(t *testing.T) {
+		var mu sync.Mutex
+		rollbacks := make(map[string][]string)
+		m := New(newClients(rollbacks, &mu)...)
+
+		rollback, err := m.BeginTransaction(context.Background(), callback, WithQuorum(90))
+
+		var hostErr *HostError
+		if assert.ErrorAs(t, err, &hostErr) {
+			assert.Equal(t, "host3", hostErr.Host)
+		}
+		assert.NoError(t, rollback(context.Background()))
+		assert.Len(t, rollbacks, 3)
+	})
+}
+
+func TestBarrier(t *testing.T) {
+	var mu sync.Mutex
+	calls := make([]string, 0)
+	newClient := func(host string, prepareDelay time.Duration, failPrepare bool) *SSHServiceStub {
+		client := NewMockSSHLikeService(host)
+		client.RunFunc = func(ctx context.Context, cmd string, options ...sshexec.SessionOption) error {
+			if cmd == "prepare" {
+				time.Sleep(prepareDelay)
+				if failPrepare {
+					return errors.New("prepare failed")
+				}
+			}
+			mu.Lock()
+			calls = append(calls, host+" "+cmd)
+			mu.Unlock()
+			return nil
+		}
+		return client
+	}
+	callback := func(ctx context.Context, tx Transaction) error {
+		if err := tx.Run(ctx, "prepare", ""); err != nil {
+			return err
+		}
+		if err := tx.Barrier(ctx); err != nil {
+			return err
+		}
+		return tx.Run(ctx, "commit", "")
+	}
+
+	t.Run("commits once every host is prepared", func(t *testing.T) {
+		calls = calls[:0]
+		m := New(newClient("host1", 0, false), newClient("host2", 500*time.Millisecond, false))
+
+		_, err := m.BeginTransaction(context.Background(), callback)
+
+		assert.NoError(t, err)
+		if assert.Len(t, calls, 4) {
+			assert.ElementsMatch(t, []string{"host1 prepare", "host2 prepare"}, calls[:2])
+			assert.ElementsMatch(t, []string{"host1 commit", "host2 commit"}, calls[2:])
+		}
+	})
+
+	t.Run("does not commit if a host failed to prepare", func(t *testing.T) {
+		calls = calls[:0]
+		m := New(newClient("host1", 0, false), newClient("host2", 500*time.Millisecond, true))
+
+		_, err := m.BeginTransaction(context.Background(), callback)
+
+		assert.Error(t, err)
+		assert.Equal(t, []string{"host1 prepare"}, calls)
+	})
+
+	t.Run("does not commit if the last host failed to prepare", func(t *testing.T) {
+		m := New(NewMockSSHLikeService("host1"), NewMockSSHLikeService("host2"))
+		var committed atomic.Bool
+
+		_, err := m.BeginTransaction(context.Background(), func(ctx context.Context, tx Transaction) error {
+			if tx.Host() == "host2" {
+				time.Sleep(50 * time.Millisecond)
+				return errors.New("prepare failed")
+			}
+			if err := tx.Barrier(ctx); err != nil {
+				return err
+			}
+			committed.Store(true)
+			return nil
+		})
+
+		assert.Error(t, err)
+		assert.False(t, committed.Load())
+	})
+
+	t.Run("does not wait for failed hosts within quorum", func(t *testing.T) {
+		calls = calls[:0]
+		m := New(newClient("host1", 0, false), newClient("host2", 0, false), newClient("host3", 500*time.Millisecond, true))
+
+		_, err := m.BeginTransaction(context.Background(), callback, WithQuorum(60))
+
+		var partialErr *PartialError
+		assert.ErrorAs(t, err, &partialErr)
+		assert.ElementsMatch(t, []string{"host1 prepare", "host2 prepare", "host1 commit", "host2 commit"}, calls)
+	})
+}
+
+func TestExecute(t *testing.T) {
+	failing := NewMockSSHLikeService("host1")
+	slow := NewMockSSHLikeService("host2")
+
+	var mu sync.Mutex
+	finished := make(map[string]bool)
+	m := New(failing, slow)
+	err := m.Execute(context.Background(), func(ctx context.Context, client sshexec.Service) error {
+		if client.Host() == "host1" {
+			return errors.New("host1 failed")
+		}
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		finished[client.Host()] = true
+		mu.Unlock()
+		return errors.New("host2 failed")
+	})
+
+	assert.ErrorContains(t, err, "host1 failed")
+	assert.ErrorContains(t, err, "host2 failed")
+	assert.True(t, finished["host2"], "Execute must wait for every host")
+}